@@ -33,3 +33,20 @@ type IRepository[TDomainObject any] interface {
 	// 获取数据库时间
 	Now() (time.Time, error)
 }
+
+// ITryRepository 通用的仓储接口，查询失败时返回错误（IRepository的查询方法会忽略错误）
+type ITryRepository[TDomainObject any] interface {
+	IRepository[TDomainObject]
+	// TryToEntity 查询实体，found表示是否找到记录
+	TryToEntity(id any) (entity TDomainObject, found bool, err error)
+	// TryToList 获取所有列表
+	TryToList() (collections.List[TDomainObject], error)
+	// TryToPageList 分页列表
+	TryToPageList(pageSize, pageIndex int) (collections.PageList[TDomainObject], error)
+	// TryCount 数量
+	TryCount() (int64, error)
+	// TryIsExists 记录是否存在
+	TryIsExists(id any) (bool, error)
+	// InTx 返回在显式的事务中执行的仓储
+	InTx(tx *Tx) ITryRepository[TDomainObject]
+}
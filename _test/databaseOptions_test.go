@@ -0,0 +1,84 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/farseer-go/data"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// 只实现了IDataDriver的第三方驱动
+type minimalDataDriver struct{}
+
+func (receiver *minimalDataDriver) GetDriver(connectionString string) gorm.Dialector {
+	return nil
+}
+
+func (receiver *minimalDataDriver) CreateIndex(tableName string, idxName string, idxField data.IdxField) string {
+	return ""
+}
+
+func TestMinimalDataDriver(t *testing.T) {
+	container.Register(func() data.IDataDriver { return &minimalDataDriver{} }, "minimal")
+
+	// 未实现IConnectionStringBuilder时，不支持结构化的配置
+	assert.Equal(t, "", data.CreateConnectionString("minimal", "127.0.0.1", 3306, "dbname", "root", "123456"))
+}
+
+func TestDatabaseOptions(t *testing.T) {
+	t.Run("由驱动生成连接字符串", func(t *testing.T) {
+		configString := data.CreateConnectionString("mysql", "127.0.0.1", 3306, "dbname", "root", "pa,ss")
+		assert.Equal(t, "DataType=mysql,PoolMaxSize=20,PoolMinSize=1,ConnectionString=root:pa,ss@tcp(127.0.0.1:3306)/dbname?charset=utf8mb4&loc=Local&parseTime=True", configString)
+	})
+
+	t.Run("结构化配置", func(t *testing.T) {
+		data.RegisterInternalContextWithOptions("options", data.DatabaseOptions{
+			DataType: "MySql",
+			Host:     "192.168.1.8",
+			Port:     3306,
+			User:     "root",
+			Password: "qwe123",
+			Database: "farseer_test",
+			Options:  map[string]string{"charset": "utf8"},
+			Pool:     data.PoolOptions{MaxSize: 5, MinSize: 1},
+			Replicas: []data.ReplicaOptions{{Host: "192.168.1.8"}},
+		})
+
+		var dbContext TestMysqlContext
+		data.InitContext(&dbContext, "options")
+		_, err := dbContext.User.TryCount()
+		assert.Nil(t, err)
+
+		db, err := dbContext.ReadOnly()
+		assert.Nil(t, err)
+		var count int64
+		assert.Nil(t, db.Table("user").Count(&count).Error)
+	})
+
+	t.Run("TLS配置", func(t *testing.T) {
+		data.RegisterInternalContextWithOptions("options_tls", data.DatabaseOptions{
+			DataType: "MySql",
+			Host:     "192.168.1.8",
+			Port:     3306,
+			User:     "root",
+			Password: "qwe123",
+			Database: "farseer_test",
+			TLS:      data.TLSOptions{Mode: data.TLSRequire},
+		})
+
+		var dbContext TestMysqlContext
+		data.InitContext(&dbContext, "options_tls")
+		_, err := dbContext.User.TryCount()
+		assert.Nil(t, err)
+	})
+}
+
+func TestRedactConnectionString(t *testing.T) {
+	dataDriver := &data.DataDriver{}
+	info, err := dataDriver.ParseConnectionString("root:qwe123@tcp(192.168.1.8:3306)/farseer_test?charset=utf8&parseTime=True&loc=Local")
+	assert.Nil(t, err)
+	assert.Equal(t, "root@192.168.1.8:3306/farseer_test", info.String())
+	assert.NotContains(t, info.String(), "qwe123")
+}
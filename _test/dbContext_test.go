@@ -137,3 +137,15 @@ func TestExecuteSql(t *testing.T) {
 	var context TestMysqlContext
 	data.InitContext(&context, "test")
 }
+
+func TestOriginalConnectFailed(t *testing.T) {
+	ctx := data.NewInternalContext("DataType=mysql,ConnectionString=root:pw@tcp(127.0.0.1:1)/db?timeout=1s")
+
+	// 连接失败时返回错误，而不是空指针异常
+	db, err := ctx.Original()
+	assert.NotNil(t, err)
+	assert.Nil(t, db)
+
+	_, err = ctx.ExecuteSql("select 1")
+	assert.NotNil(t, err)
+}
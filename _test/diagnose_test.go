@@ -0,0 +1,96 @@
+package test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/farseer-go/data"
+	"github.com/farseer-go/fs/container"
+	"github.com/farseer-go/fs/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnose(t *testing.T) {
+	t.Run("全部通过", func(t *testing.T) {
+		report := data.Diagnose("DataType=MySql,ConnectionString=root:qwe123@tcp(192.168.1.8:3306)/farseer_test?charset=utf8&parseTime=True&loc=Local", 0)
+		assert.True(t, report.Success, report.String())
+		assert.Nil(t, report.FailedStage())
+		assert.NotEmpty(t, report.ServerVersion)
+		assert.Len(t, report.Stages, 9)
+	})
+
+	t.Run("数据库不存在", func(t *testing.T) {
+		report := data.Diagnose("DataType=MySql,ConnectionString=root:qwe123@tcp(192.168.1.8:3306)/farseer_not_exists?charset=utf8", 0)
+		assert.False(t, report.Success)
+		assert.Equal(t, data.StageDatabase, report.FailedStage().Name)
+	})
+
+	t.Run("TCP连接失败", func(t *testing.T) {
+		report := data.Diagnose("DataType=MySql,ConnectionString=root:qwe123@tcp(127.0.0.1:1)/farseer_test", time.Second)
+		assert.Equal(t, data.StageTcp, report.FailedStage().Name)
+		// 之后的阶段都跳过
+		assert.Equal(t, data.DiagnosticSkip, report.Stages[len(report.Stages)-1].Status)
+	})
+
+	t.Run("TLS证书不存在", func(t *testing.T) {
+		report := data.Diagnose("DataType=MySql,TlsMode=verify-full,TlsCaFile=/not/exists/ca.pem,ConnectionString=root:qwe123@tcp(192.168.1.8:3306)/farseer_test?charset=utf8", 5*time.Second)
+		assert.False(t, report.Success)
+		assert.Equal(t, data.StageTls, report.FailedStage().Name)
+	})
+
+	t.Run("只读用户", func(t *testing.T) {
+		var context TestMysqlContext
+		data.InitContext(&context, "test")
+		_, _ = context.ExecuteSql("CREATE USER IF NOT EXISTS 'farseer_ro'@'%' IDENTIFIED BY 'qwe123'")
+		_, err := context.ExecuteSql("GRANT SELECT ON farseer_test.* TO 'farseer_ro'@'%'")
+		assert.Nil(t, err)
+
+		report := data.Diagnose("DataType=MySql,ConnectionString=farseer_ro:qwe123@tcp(192.168.1.8:3306)/farseer_test?charset=utf8", 0)
+		assert.False(t, report.Success)
+		assert.Equal(t, data.StageWrite, report.FailedStage().Name)
+		// 写权限失败时，仍然获取版本
+		assert.Equal(t, data.StageVersion, report.Stages[len(report.Stages)-1].Name)
+		assert.Equal(t, data.DiagnosticPass, report.Stages[len(report.Stages)-1].Status)
+		assert.NotEmpty(t, report.ServerVersion)
+	})
+}
+
+func TestCheckWithTimeout(t *testing.T) {
+	checker := container.Resolve[core.IConnectionChecker]("data")
+	poolCount := len(data.GetPoolStats())
+	success, err := checker.CheckWithTimeout("DataType=MySql,ConnectionString=root:qwe123@tcp(192.168.1.8:3306)/farseer_test?charset=utf8", 5*time.Second)
+	assert.True(t, success)
+	assert.Nil(t, err)
+
+	// 检查不会缓存连接池
+	assert.Equal(t, poolCount, len(data.GetPoolStats()))
+
+	t.Run("主机接受连接后不响应", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		defer func() { _ = listener.Close() }()
+		// 接受连接后不发送握手包，驱动会一直等待
+		go func() {
+			var conns []net.Conn
+			defer func() {
+				for _, conn := range conns {
+					_ = conn.Close()
+				}
+			}()
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				conns = append(conns, conn)
+			}
+		}()
+
+		startAt := time.Now()
+		success, err := checker.CheckWithTimeout("DataType=MySql,ConnectionString=root:qwe123@tcp("+listener.Addr().String()+")/farseer_test", time.Second)
+		assert.False(t, success)
+		assert.NotNil(t, err)
+		assert.Less(t, time.Since(startAt), 3*time.Second)
+	})
+}
@@ -3,12 +3,27 @@ package test
 import (
 	"testing"
 
+	"github.com/farseer-go/collections"
 	"github.com/farseer-go/data"
 	"github.com/stretchr/testify/assert"
 )
 
 type TestDuplicateContext struct {
 	Account data.TableSet[AccountPO] `data:"migrate"`
+	Member  data.TableSet[MemberPO]  `data:"migrate"`
+}
+
+// 自增主键 + 唯一索引
+type MemberPO struct {
+	Id   int    `gorm:"primaryKey;autoIncrement"`
+	Name string `gorm:"type:varchar(32)"`
+}
+
+// 创建索引
+func (*MemberPO) CreateIndex() map[string]data.IdxField {
+	return map[string]data.IdxField{
+		"idx_member_name": {true, "name"},
+	}
 }
 
 type AccountPO struct {
@@ -45,3 +60,23 @@ func TestDuplicateKey(t *testing.T) {
 		assert.Error(t, err, "insert error")
 	}
 }
+
+func TestInsertIgnoreUniqueIndex(t *testing.T) {
+	context := data.NewContext[TestDuplicateContext]("test")
+	_, _ = context.Member.Delete()
+
+	rowsAffected, err := context.Member.InsertIgnore(&MemberPO{Name: "aaa"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), rowsAffected)
+
+	// 自增主键为0，唯一索引冲突时忽略
+	rowsAffected, err = context.Member.InsertIgnore(&MemberPO{Name: "aaa"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), rowsAffected)
+
+	// 批量新增时，只跳过冲突的记录
+	rowsAffected, err = context.Member.InsertIgnoreList(collections.NewList(MemberPO{Name: "aaa"}, MemberPO{Name: "bbb"}, MemberPO{Name: "ccc"}), 2)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), rowsAffected)
+	assert.Equal(t, int64(3), context.Member.Count())
+}
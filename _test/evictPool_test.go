@@ -0,0 +1,57 @@
+package test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/farseer-go/data"
+	"github.com/farseer-go/fs/configure"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+const tenantConnectionString = "DataType=MySql,PoolMaxSize=2,DrainSeconds=1,ConnectionString=root:qwe123@tcp(192.168.1.8:3306)/farseer_test?charset=utf8&parseTime=True&loc=Local&readTimeout="
+
+func TestOpenSingleFlight(t *testing.T) {
+	ctx := data.NewInternalContext(tenantConnectionString + "31s")
+	var wg sync.WaitGroup
+	lst := make([]*gorm.DB, 10)
+	for i := range lst {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			lst[index], _ = ctx.Original()
+		}(i)
+	}
+	wg.Wait()
+
+	first, _ := lst[0].DB()
+	for _, gormDB := range lst {
+		sqlDB, _ := gormDB.DB()
+		assert.Same(t, first, sqlDB)
+	}
+}
+
+func TestEvictOverLimit(t *testing.T) {
+	// 只允许再打开一个连接池
+	configure.SetDefault("Data.MaxPools", len(data.GetPoolStats())+1)
+	defer configure.SetDefault("Data.MaxPools", 0)
+
+	tenantA := data.NewInternalContext(tenantConnectionString + "32s")
+	gormDB, err := tenantA.Original()
+	assert.Nil(t, err)
+	sqlDBA, _ := gormDB.DB()
+
+	// 打开第二个租户时，淘汰最久未使用的租户A
+	tenantB := data.NewInternalContext(tenantConnectionString + "33s")
+	gormDB, err = tenantB.Original()
+	assert.Nil(t, err)
+	assert.Nil(t, gormDB.Exec("select 1").Error)
+	assert.Eventually(t, func() bool { return sqlDBA.Ping() != nil }, 3*time.Second, 100*time.Millisecond)
+
+	// 被淘汰后，再次使用时重新打开
+	gormDB, err = tenantA.Original()
+	assert.Nil(t, err)
+	assert.Nil(t, gormDB.Exec("select 1").Error)
+}
@@ -0,0 +1,38 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/farseer-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailover(t *testing.T) {
+	// 主库不可用时，切换到第二个主机
+	data.RegisterInternalContextWithOptions("failover", data.DatabaseOptions{
+		DataType:           "MySql",
+		Hosts:              []data.HostOptions{{Host: "127.0.0.1", Port: 1}, {Host: "192.168.1.8", Port: 3306}},
+		User:               "root",
+		Password:           "qwe123",
+		Database:           "farseer_test",
+		Options:            map[string]string{"timeout": "1s"},
+		TargetSessionAttrs: "read-write",
+	})
+
+	var dbContext TestMysqlContext
+	data.InitContext(&dbContext, "failover")
+	_, err := dbContext.User.TryCount()
+	assert.Nil(t, err)
+
+	// 所有主机都不可用
+	ctx, err := data.NewInternalContextWithOptions(data.DatabaseOptions{
+		DataType: "MySql",
+		Hosts:    []data.HostOptions{{Host: "127.0.0.1", Port: 1}, {Host: "127.0.0.1", Port: 2}},
+		User:     "root",
+		Database: "farseer_test",
+		Options:  map[string]string{"timeout": "1s"},
+	})
+	assert.Nil(t, err)
+	_, err = ctx.Original()
+	assert.ErrorIs(t, err, data.ErrConnection)
+}
@@ -0,0 +1,60 @@
+package test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/farseer-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock(t *testing.T) {
+	var dbContext TestMysqlContext
+	data.InitContext(&dbContext, "test")
+
+	lock, err := dbContext.Lock("test_lock", time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, "test_lock", lock.Name())
+
+	// 其它协程（其它连接）获取不到
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, acquired, err := dbContext.TryLock("test_lock")
+		assert.Nil(t, err)
+		assert.False(t, acquired)
+
+		_, err = dbContext.Lock("test_lock", time.Second)
+		assert.True(t, errors.Is(err, data.ErrLockTimeout))
+	}()
+	wg.Wait()
+
+	assert.Nil(t, lock.Unlock())
+	// 重复释放
+	assert.Nil(t, lock.Unlock())
+
+	lock2, acquired, err := dbContext.TryLock("test_lock")
+	assert.Nil(t, err)
+	assert.True(t, acquired)
+	assert.Nil(t, lock2.Unlock())
+}
+
+func TestLockSubSecondTimeout(t *testing.T) {
+	var dbContext TestMysqlContext
+	data.InitContext(&dbContext, "test")
+
+	lock, err := dbContext.Lock("test_lock_sub_second", time.Second)
+	assert.Nil(t, err)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		_ = lock.Unlock()
+	}()
+
+	// 不足1秒的等待时间向上取整，不会被截断为不等待
+	lock2, err := dbContext.Lock("test_lock_sub_second", 800*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Nil(t, lock2.Unlock())
+}
@@ -0,0 +1,68 @@
+package test
+
+import (
+	"embed"
+	"testing"
+
+	"github.com/farseer-go/data"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+func TestMigration(t *testing.T) {
+	data.RegisterInternalContext("migrate", "DataType=MySql,PoolMaxSize=5,PoolMinSize=1,ConnectionString=root:qwe123@tcp(192.168.1.8:3306)/farseer_test?charset=utf8&parseTime=True&loc=Local")
+	var dbContext TestMysqlContext
+	data.InitContext(&dbContext, "migrate")
+
+	db, _ := dbContext.Original()
+	db.Exec("DROP TABLE IF EXISTS migrate_user")
+	db.Exec("DELETE FROM data_migration_history WHERE key_name = 'migrate'")
+
+	assert.Nil(t, data.RegisterMigrationFS("migrate", migrationFS, "migrations"))
+	assert.Nil(t, data.RegisterMigration("migrate", data.Migration{
+		Version: 3,
+		Name:    "backfill_user_name",
+		Up: func(db *gorm.DB) error {
+			return db.Exec("UPDATE migrate_user SET user_name = ? WHERE id = 1", "backfill").Error
+		},
+		Down: func(db *gorm.DB) error {
+			return db.Exec("UPDATE migrate_user SET user_name = ? WHERE id = 1", "a;b").Error
+		},
+	}))
+	// 版本号重复
+	assert.NotNil(t, data.RegisterMigration("migrate", data.Migration{Version: 3, Name: "duplicate", Up: func(db *gorm.DB) error { return nil }}))
+
+	t.Run("升级", func(t *testing.T) {
+		assert.Nil(t, dbContext.Migrate())
+		var userName string
+		db.Raw("SELECT user_name FROM migrate_user WHERE id = 1").Scan(&userName)
+		assert.Equal(t, "backfill", userName)
+		db.Raw("SELECT user_name FROM migrate_user WHERE id = 2").Scan(&userName)
+		assert.Equal(t, "it's", userName)
+
+		// 已执行的不会重复执行
+		assert.Nil(t, dbContext.Migrate())
+	})
+
+	t.Run("回滚", func(t *testing.T) {
+		assert.Nil(t, dbContext.RollbackMigration(1))
+		var userName string
+		db.Raw("SELECT user_name FROM migrate_user WHERE id = 1").Scan(&userName)
+		assert.Equal(t, "a;b", userName)
+
+		assert.Nil(t, dbContext.MigrateTo(1))
+		var count int64
+		db.Raw("SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = 'farseer_test' AND TABLE_NAME = 'migrate_user' AND COLUMN_NAME = 'name'").Scan(&count)
+		assert.Equal(t, int64(1), count)
+
+		assert.Nil(t, dbContext.MigrateTo(3))
+	})
+
+	t.Run("脚本被修改", func(t *testing.T) {
+		db.Exec("UPDATE data_migration_history SET checksum = 'modified' WHERE key_name = 'migrate' AND version = 1")
+		assert.ErrorContains(t, dbContext.Migrate(), "校验和不一致")
+	})
+}
@@ -0,0 +1 @@
+DROP TABLE migrate_user;
@@ -0,0 +1,7 @@
+-- 创建表；注释中的分号不拆分
+CREATE TABLE migrate_user (
+    id   INT PRIMARY KEY,
+    name VARCHAR(32) NOT NULL
+);
+
+INSERT INTO migrate_user (id, name) VALUES (1, 'a;b'), (2, 'it''s');
@@ -0,0 +1 @@
+ALTER TABLE migrate_user RENAME COLUMN user_name TO name;
@@ -0,0 +1 @@
+ALTER TABLE migrate_user CHANGE COLUMN name user_name VARCHAR(32) NOT NULL;
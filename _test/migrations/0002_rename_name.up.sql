@@ -0,0 +1 @@
+ALTER TABLE migrate_user RENAME COLUMN name TO user_name;
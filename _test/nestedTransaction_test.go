@@ -0,0 +1,112 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/farseer-go/collections"
+	"github.com/farseer-go/data"
+	"github.com/farseer-go/fs/exception"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNestedTransaction(t *testing.T) {
+	var context TestMysqlContext
+	data.InitContext(&context, "test")
+
+	newUser := func(name string) *UserPO {
+		return &UserPO{Name: name, Age: 18, Specialty: collections.NewList("go"), Attribute: collections.NewDictionary[string, string]()}
+	}
+	clean := func() {
+		_, _ = context.User.Where("Name like ?", "nested%").Delete()
+	}
+	clean()
+	defer clean()
+
+	t.Run("内层失败只回滚到保存点", func(t *testing.T) {
+		context.Transaction(func() {
+			_ = context.User.Insert(newUser("nested_outer"))
+			_ = exception.TryCatch(func() {
+				context.Transaction(func() {
+					_ = context.User.Insert(newUser("nested_inner"))
+					exception.ThrowRefuseException("内层失败")
+				})
+			})
+		})
+		assert.True(t, context.User.Where("Name = ?", "nested_outer").IsExists())
+		assert.False(t, context.User.Where("Name = ?", "nested_inner").IsExists())
+	})
+
+	t.Run("内层成功随外层提交", func(t *testing.T) {
+		context.Transaction(func() {
+			context.Transaction(func() {
+				_ = context.User.Insert(newUser("nested_inner2"))
+			})
+		})
+		assert.True(t, context.User.Where("Name = ?", "nested_inner2").IsExists())
+	})
+
+	t.Run("RequiresNew不受外层回滚影响", func(t *testing.T) {
+		assert.Panics(t, func() {
+			context.Transaction(func() {
+				_ = context.User.Insert(newUser("nested_outer3"))
+				context.TransactionWithPropagation(data.PropagationRequiresNew, func() {
+					_ = context.User.Insert(newUser("nested_new3"))
+				})
+				exception.ThrowRefuseException("外层失败")
+			})
+		})
+		assert.False(t, context.User.Where("Name = ?", "nested_outer3").IsExists())
+		assert.True(t, context.User.Where("Name = ?", "nested_new3").IsExists())
+	})
+}
+
+func TestTransactionCallback(t *testing.T) {
+	var context TestMysqlContext
+	data.InitContext(&context, "test")
+
+	t.Run("不在事务中立即执行", func(t *testing.T) {
+		committed := false
+		context.OnCommit(func() { committed = true })
+		assert.True(t, committed)
+	})
+
+	t.Run("不在事务中立即执行回滚回调", func(t *testing.T) {
+		rolledBack := false
+		context.OnRollback(func() { rolledBack = true })
+		assert.True(t, rolledBack)
+	})
+
+	t.Run("提交后执行", func(t *testing.T) {
+		var events []string
+		context.Transaction(func() {
+			context.OnCommit(func() { events = append(events, "commit") })
+			context.OnRollback(func() { events = append(events, "rollback") })
+			assert.Empty(t, events)
+		})
+		assert.Equal(t, []string{"commit"}, events)
+	})
+
+	t.Run("回滚后执行", func(t *testing.T) {
+		var events []string
+		_ = exception.TryCatch(func() {
+			context.Transaction(func() {
+				context.OnCommit(func() { events = append(events, "commit") })
+				context.OnRollback(func() { events = append(events, "rollback") })
+				exception.ThrowRefuseException("回滚")
+			})
+		})
+		assert.Equal(t, []string{"rollback"}, events)
+	})
+
+	t.Run("嵌套事务等外层提交", func(t *testing.T) {
+		var events []string
+		context.Transaction(func() {
+			context.Transaction(func() {
+				context.OnCommit(func() { events = append(events, "inner") })
+			})
+			assert.Empty(t, events)
+			context.OnCommit(func() { events = append(events, "outer") })
+		})
+		assert.Equal(t, []string{"inner", "outer"}, events)
+	})
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/farseer-go/data"
+	"github.com/farseer-go/fs/container"
+	"github.com/farseer-go/fs/exception"
+	"github.com/stretchr/testify/assert"
+)
+
+type testOutboxPublisher struct {
+	lock     sync.Mutex
+	messages []data.OutboxMessage
+}
+
+func (receiver *testOutboxPublisher) Publish(message data.OutboxMessage) error {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	receiver.messages = append(receiver.messages, message)
+	return nil
+}
+
+func (receiver *testOutboxPublisher) topics() []string {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	var topics []string
+	for _, message := range receiver.messages {
+		topics = append(topics, message.Topic)
+	}
+	return topics
+}
+
+func TestOutbox(t *testing.T) {
+	var dbContext TestMysqlContext
+	data.InitContext(&dbContext, "test")
+
+	publisher := &testOutboxPublisher{}
+	container.RegisterInstance[data.IOutboxPublisher](publisher, "test")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.Nil(t, dbContext.StartOutboxDispatcher(ctx, data.OutboxOptions{PollInterval: 100 * time.Millisecond}))
+
+	// 提交的消息会被投递
+	dbContext.Transaction(func() {
+		assert.Nil(t, dbContext.Enqueue("outbox_commit", map[string]int{"id": 1}))
+	})
+	// 回滚的消息不会被投递
+	_ = exception.TryCatch(func() {
+		dbContext.Transaction(func() {
+			_ = dbContext.Enqueue("outbox_rollback", "rollback")
+			exception.ThrowRefuseException("回滚")
+		})
+	})
+
+	assert.Eventually(t, func() bool {
+		return len(publisher.topics()) > 0
+	}, 5*time.Second, 100*time.Millisecond)
+	assert.Contains(t, publisher.topics(), "outbox_commit")
+	assert.NotContains(t, publisher.topics(), "outbox_rollback")
+}
+
+// 每个租户一个数据库
+type databaseTenantResolver struct{}
+
+func (receiver *databaseTenantResolver) GetTenant() string {
+	return data.CurrentTenant()
+}
+
+func (receiver *databaseTenantResolver) Resolve(tenant string) (data.TenantRoute, error) {
+	return data.TenantRoute{Strategy: data.TenantDatabase, ConnectionString: "DataType=MySql,PoolMaxSize=3,ConnectionString=root:qwe123@tcp(192.168.1.8:3306)/farseer_test?charset=utf8&parseTime=True&loc=Local&timeout=2s"}, nil
+}
+
+func TestOutboxTenantDatabase(t *testing.T) {
+	data.RegisterInternalContext("outbox_tenant", "DataType=MySql,PoolMaxSize=3,ConnectionString=root:qwe123@tcp(192.168.1.8:3306)/farseer_test?charset=utf8&parseTime=True&loc=Local")
+	container.Register(func() data.ITenantResolver { return &databaseTenantResolver{} }, "outbox_tenant")
+	dbContext := data.NewContext[TestTenantContext]("outbox_tenant")
+	data.SetTenant("t1")
+	defer data.ClearTenant()
+
+	// 不在事务中时，写入注册的数据库
+	assert.Nil(t, dbContext.Enqueue("outbox_tenant", "tenant"))
+
+	// 租户数据库的事务无法与发件箱一起提交
+	tx, err := dbContext.BeginTx(context.Background(), nil)
+	assert.Nil(t, err)
+	assert.NotNil(t, tx.Enqueue("outbox_tenant", "tenant"))
+	assert.Nil(t, tx.Rollback())
+}
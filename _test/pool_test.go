@@ -0,0 +1,44 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/farseer-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPool(t *testing.T) {
+	data.RegisterInternalContext("pool", "DataType=MySql,PoolMaxSize=3,PoolMinSize=2,PoolMaxIdle=2,PoolMaxLifetime=60,ConnectionString=root:qwe123@tcp(192.168.1.8:3306)/farseer_test?charset=utf8&parseTime=True&loc=Local")
+	var dbContext TestMysqlContext
+	data.InitContext(&dbContext, "pool")
+
+	gormDB, err := dbContext.Original()
+	assert.Nil(t, err)
+	sqlDB, err := gormDB.DB()
+	assert.Nil(t, err)
+
+	// 不再强制最小10个
+	assert.Equal(t, 3, sqlDB.Stats().MaxOpenConnections)
+	// 启动后预先创建最小连接数
+	assert.Eventually(t, func() bool { return sqlDB.Stats().OpenConnections >= 2 }, 5*time.Second, 100*time.Millisecond)
+}
+
+func TestPoolStats(t *testing.T) {
+	var dbContext TestMysqlContext
+	data.InitContext(&dbContext, "test")
+	_, _ = dbContext.User.TryCount()
+
+	stats, err := dbContext.PoolStats()
+	assert.Nil(t, err)
+	assert.Equal(t, "test", stats.KeyName)
+	assert.True(t, stats.Open > 0)
+
+	var exists bool
+	for _, poolStats := range data.GetPoolStats() {
+		if poolStats.KeyName == "test" {
+			exists = true
+		}
+	}
+	assert.True(t, exists)
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/farseer-go/data"
+	"github.com/farseer-go/fs/configure"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReRegister(t *testing.T) {
+	data.RegisterInternalContext("reload", "DataType=MySql,PoolMaxSize=3,DrainSeconds=1,ConnectionString=root:qwe123@tcp(192.168.1.8:3306)/farseer_test?charset=utf8&parseTime=True&loc=Local")
+	var dbContext TestMysqlContext
+	data.InitContext(&dbContext, "reload")
+	_, _ = dbContext.User.TryCount()
+
+	oldDB, _ := dbContext.Original()
+	oldSqlDB, _ := oldDB.DB()
+
+	// 重新注册后切换到新的连接池，旧的连接池等进行中的查询完成后关闭
+	data.RegisterInternalContext("reload", "DataType=MySql,PoolMaxSize=5,DrainSeconds=1,ConnectionString=root:qwe123@tcp(192.168.1.8:3306)/farseer_test?charset=utf8&parseTime=True&loc=Local")
+	newDB, err := dbContext.Original()
+	assert.Nil(t, err)
+	newSqlDB, _ := newDB.DB()
+	assert.NotSame(t, oldSqlDB, newSqlDB)
+	assert.Equal(t, 5, newSqlDB.Stats().MaxOpenConnections)
+	assert.Eventually(t, func() bool { return oldSqlDB.Ping() != nil }, 3*time.Second, 100*time.Millisecond)
+
+	// 旧的上下文继续使用新的连接池
+	_, err = dbContext.User.TryCount()
+	assert.Nil(t, err)
+
+	// 新的连接池创建失败时，继续使用旧的配置和连接池
+	assert.Panics(t, func() {
+		data.RegisterInternalContext("reload", "DataType=MySql,PoolMaxSize=7,ConnectionString=root:qwe123@tcp(127.0.0.1:1)/farseer_test?charset=utf8&parseTime=True&loc=Local")
+	})
+	db, err := dbContext.Original()
+	assert.Nil(t, err)
+	sqlDB, _ := db.DB()
+	assert.Same(t, newSqlDB, sqlDB)
+	_, err = dbContext.User.TryCount()
+	assert.Nil(t, err)
+}
+
+func TestApplyDatabaseConfig(t *testing.T) {
+	configure.SetDefault("Database.apply", "DataType=MySql,PoolMaxSize=3,DrainSeconds=1,ConnectionString=root:qwe123@tcp(192.168.1.8:3306)/farseer_test?charset=utf8&parseTime=True&loc=Local")
+	data.ApplyDatabaseConfig()
+	var dbContext TestMysqlContext
+	data.InitContext(&dbContext, "apply")
+	_, err := dbContext.User.TryCount()
+	assert.Nil(t, err)
+
+	// 配置有变化时，切换到新的连接池
+	configure.SetDefault("Database.apply", "DataType=MySql,PoolMaxSize=4,DrainSeconds=1,ConnectionString=root:qwe123@tcp(192.168.1.8:3306)/farseer_test?charset=utf8&parseTime=True&loc=Local")
+	data.ApplyDatabaseConfig()
+	db, err := dbContext.Original()
+	assert.Nil(t, err)
+	sqlDB, _ := db.DB()
+	assert.Equal(t, 4, sqlDB.Stats().MaxOpenConnections)
+}
@@ -0,0 +1,47 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/farseer-go/data"
+	"github.com/farseer-go/fs/container"
+	"github.com/farseer-go/fs/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryableTransaction(t *testing.T) {
+	data.RegisterInternalContext("retry", "DataType=MySql,PoolMaxSize=5,PoolMinSize=1,RetryMaxAttempts=3,RetryBackoff=1,ConnectionString=root:qwe123@tcp(192.168.1.8:3306)/farseer_test?charset=utf8&parseTime=True&loc=Local")
+	ctx := container.Resolve[core.ITransaction]("retry").(data.IInternalContext)
+
+	t.Run("死锁时重试", func(t *testing.T) {
+		attempt := 0
+		ctx.RetryableTransaction(func() {
+			if attempt++; attempt < 3 {
+				panic(data.ErrDeadlock)
+			}
+		})
+		assert.Equal(t, 3, attempt)
+	})
+
+	t.Run("超过重试次数", func(t *testing.T) {
+		attempt := 0
+		assert.Panics(t, func() {
+			ctx.RetryableTransaction(func() {
+				attempt++
+				panic(data.ErrSerialization)
+			})
+		})
+		assert.Equal(t, 3, attempt)
+	})
+
+	t.Run("不可重试的错误", func(t *testing.T) {
+		attempt := 0
+		assert.Panics(t, func() {
+			ctx.RetryableTransaction(func() {
+				attempt++
+				panic(data.ErrDuplicateKey)
+			})
+		})
+		assert.Equal(t, 1, attempt)
+	})
+}
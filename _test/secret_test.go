@@ -0,0 +1,50 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/farseer-go/data"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+)
+
+type testSecretProvider struct{}
+
+func (receiver *testSecretProvider) GetSecret(key string) (string, error) {
+	return map[string]string{"db/user": "root"}[key], nil
+}
+
+func TestSecret(t *testing.T) {
+	_ = os.Setenv("FARSEER_TEST_DB_PASSWORD", "qwe123")
+	container.Register(func() data.ISecretProvider { return &testSecretProvider{} }, "vault")
+
+	t.Run("字符串配置", func(t *testing.T) {
+		data.RegisterInternalContext("secret", "DataType=MySql,PoolMaxSize=5,ConnectionString=${VAULT:db/user}:${ENV:FARSEER_TEST_DB_PASSWORD}@tcp(192.168.1.8:3306)/farseer_test?charset=utf8&parseTime=True&loc=Local")
+		var dbContext TestMysqlContext
+		data.InitContext(&dbContext, "secret")
+		_, err := dbContext.User.TryCount()
+		assert.Nil(t, err)
+	})
+
+	t.Run("结构化配置", func(t *testing.T) {
+		data.RegisterInternalContextWithOptions("secretOptions", data.DatabaseOptions{
+			DataType: "mysql",
+			Host:     "192.168.1.8",
+			Port:     3306,
+			User:     "${VAULT:db/user}",
+			Password: "${ENV:FARSEER_TEST_DB_PASSWORD}",
+			Database: "farseer_test",
+		})
+		var dbContext TestMysqlContext
+		data.InitContext(&dbContext, "secretOptions")
+		_, err := dbContext.User.TryCount()
+		assert.Nil(t, err)
+	})
+
+	t.Run("密钥不存在", func(t *testing.T) {
+		assert.Panics(t, func() {
+			data.RegisterInternalContext("secretMissing", "DataType=MySql,ConnectionString=root:${ENV:FARSEER_TEST_NOT_EXISTS}@tcp(192.168.1.8:3306)/farseer_test")
+		})
+	})
+}
@@ -1,6 +1,8 @@
 package test
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/farseer-go/collections"
@@ -263,6 +265,91 @@ func TestTableSet(t *testing.T) {
 		assert.Equal(t, false, user.IsEnable)
 	})
 
+	t.Run("Returning", func(t *testing.T) {
+		po := UserPO{Name: "returning", Age: 20, Specialty: collections.NewList("go"), Attribute: collections.NewDictionary[string, string]()}
+		assert.Nil(t, context.User.Returning().Insert(&po))
+		assert.Less(t, 0, po.Id)
+		assert.Equal(t, "returning", po.Name)
+
+		po.Age = 21
+		lst, err := context.User.Where("Id = ?", po.Id).Returning("id", "age").UpdateReturning(po)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, lst.Count())
+		assert.Equal(t, 21, lst.First().Age)
+
+		lst, err = context.User.Where("Id = ?", po.Id).Returning().DeleteReturning()
+		assert.Nil(t, err)
+		assert.Equal(t, 1, lst.Count())
+		assert.Equal(t, "returning", lst.First().Name)
+		assert.False(t, context.User.Where("Id = ?", po.Id).IsExists())
+	})
+
+	t.Run("BulkLoad", func(t *testing.T) {
+		csv := "name,age,is_enable\nbulk1,18,true\nbulk2,19,false\n"
+		rowsAffected, err := context.User.BulkLoad(data.NewCsvSource(strings.NewReader(csv)))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), rowsAffected)
+		assert.Equal(t, 19, context.User.Where("Name = ?", "bulk2").ToEntity().Age)
+
+		rowsAffected, err = context.User.BulkLoad(collections.NewList(UserPO{Name: "bulk3", Age: 20}, UserPO{Name: "bulk4", Age: 21}))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), rowsAffected)
+
+		// 进度回调
+		source := make(chan UserPO)
+		go func() {
+			for i := 0; i < 10000; i++ {
+				source <- UserPO{Name: "bulk_progress", Age: i}
+			}
+			close(source)
+		}()
+		var progress []int64
+		rowsAffected, err = context.User.BulkLoad(source, func(readCount int64) { progress = append(progress, readCount) })
+		assert.Nil(t, err)
+		assert.Equal(t, int64(10000), rowsAffected)
+		assert.Equal(t, []int64{10000}, progress)
+
+		// 数据源提供了自增主键时，与InsertList一致写入
+		rowsAffected, err = context.User.BulkLoad([]UserPO{{Id: 900001, Name: "bulk_id1"}, {Id: 900002, Name: "bulk_id2"}})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), rowsAffected)
+		assert.Equal(t, "bulk_id2", context.User.Where("Id = ?", 900002).ToEntity().Name)
+		_, _ = context.User.Where("Name like ?", "bulk%").Delete()
+	})
+
+	t.Run("Try", func(t *testing.T) {
+		count, err := context.User.TryCount()
+		assert.Nil(t, err)
+		assert.Equal(t, context.User.Count(), count)
+
+		_, found, err := context.User.Where("Name = ?", "not-exists").FindOne()
+		assert.Nil(t, err)
+		assert.False(t, found)
+
+		user, found, err := context.User.Where("Name = ?", "steden").FindOne()
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "steden", user.Name)
+
+		name, err := context.User.Where("Id = ?", user.Id).TryGetString("name")
+		assert.Nil(t, err)
+		assert.Equal(t, "steden", name)
+
+		// 字段不存在时，应返回错误而不是空的结果
+		_, err = context.User.Where("not_exists_field = ?", 1).TryToList()
+		assert.NotNil(t, err)
+		_, err = context.User.TryGetInt("not_exists_field")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("ErrDuplicateKey", func(t *testing.T) {
+		po := UserPO{Name: "duplicate", Age: 20, Specialty: collections.NewList("go"), Attribute: collections.NewDictionary[string, string]()}
+		assert.Nil(t, context.User.Insert(&po))
+		err := context.User.Insert(&po)
+		assert.True(t, errors.Is(err, data.ErrDuplicateKey))
+		_, _ = context.User.Where("Id = ?", po.Id).Delete()
+	})
+
 	t.Run("正常事务", func(t *testing.T) {
 		// 清空所有数据
 		_, _ = context.User.Where("Id > ?", 0).WhereIgnoreLessZero("Id = ?", 0).WhereIgnoreNil("Id = ?", nil).Delete()
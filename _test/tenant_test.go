@@ -0,0 +1,109 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/farseer-go/collections"
+	"github.com/farseer-go/data"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+)
+
+type TestTenantContext struct {
+	data.IInternalContext
+	Order data.TableSet[TenantOrderPO] `data:"name=tenant_order;migrate"`
+}
+
+type TenantOrderPO struct {
+	Id       int    `gorm:"primaryKey;autoIncrement"`
+	TenantId string `gorm:"type:varchar(32)"`
+	Name     string `gorm:"type:varchar(32)"`
+}
+
+// 共享表的多租户：按tenant_id区分
+type sharedTenantResolver struct{}
+
+func (receiver *sharedTenantResolver) GetTenant() string {
+	return data.CurrentTenant()
+}
+
+func (receiver *sharedTenantResolver) Resolve(tenant string) (data.TenantRoute, error) {
+	return data.TenantRoute{Strategy: data.TenantSharedTable}, nil
+}
+
+func TestTenantSharedTable(t *testing.T) {
+	data.RegisterInternalContext("tenant", "DataType=MySql,PoolMaxSize=3,ConnectionString=root:qwe123@tcp(192.168.1.8:3306)/farseer_test?charset=utf8&parseTime=True&loc=Local")
+	container.Register(func() data.ITenantResolver { return &sharedTenantResolver{} }, "tenant")
+	dbContext := data.NewContext[TestTenantContext]("tenant")
+	defer data.ClearTenant()
+
+	// 没有租户时，不路由
+	_, _ = dbContext.Order.Delete()
+
+	// 新增时自动写入租户字段
+	data.SetTenant("t1")
+	assert.Nil(t, dbContext.Order.Insert(&TenantOrderPO{Name: "order1"}))
+	data.SetTenant("t2")
+	assert.Nil(t, dbContext.Order.Insert(&TenantOrderPO{Name: "order2"}))
+	assert.Nil(t, dbContext.Order.Insert(&TenantOrderPO{Name: "order3"}))
+
+	// 查询时按租户过滤
+	assert.Equal(t, int64(2), dbContext.Order.Count())
+	data.SetTenant("t1")
+	assert.Equal(t, int64(1), dbContext.Order.Count())
+	assert.Equal(t, "t1", dbContext.Order.ToEntity().TenantId)
+
+	data.ClearTenant()
+	assert.Equal(t, int64(3), dbContext.Order.Count())
+
+	t.Run("显式的事务", func(t *testing.T) {
+		data.SetTenant("t3")
+		defer data.ClearTenant()
+
+		tx, err := dbContext.BeginTx(context.Background(), nil)
+		assert.Nil(t, err)
+		assert.Nil(t, dbContext.Order.InTx(tx).Insert(&TenantOrderPO{Name: "order4"}))
+		// 事务中同样按租户过滤
+		assert.Equal(t, int64(1), dbContext.Order.InTx(tx).Count())
+		assert.Equal(t, "t3", dbContext.Order.InTx(tx).ToEntity().TenantId)
+		assert.Nil(t, tx.Commit())
+
+		data.SetTenant("t1")
+		assert.Equal(t, int64(1), dbContext.Order.Count())
+	})
+
+	t.Run("批量新增", func(t *testing.T) {
+		data.SetTenant("t4")
+		defer data.ClearTenant()
+
+		_, err := dbContext.Order.InsertList(collections.NewList(TenantOrderPO{Name: "list1"}, TenantOrderPO{Name: "list2"}, TenantOrderPO{Name: "list3"}), 10)
+		assert.Nil(t, err)
+		_, err = dbContext.Order.InsertIgnoreList(collections.NewList(TenantOrderPO{Name: "ignore1"}, TenantOrderPO{Name: "ignore2"}), 10)
+		assert.Nil(t, err)
+
+		// 每一行都写入了租户字段
+		data.ClearTenant()
+		lst := dbContext.Order.Where("name like ? or name like ?", "list%", "ignore%").ToList()
+		assert.Equal(t, 5, lst.Count())
+		lst.Foreach(func(item *TenantOrderPO) {
+			assert.Equal(t, "t4", item.TenantId)
+		})
+	})
+
+	t.Run("批量导入", func(t *testing.T) {
+		data.SetTenant("t5")
+		defer data.ClearTenant()
+
+		rowsAffected, err := dbContext.Order.BulkLoad(collections.NewList(TenantOrderPO{Name: "bulk1"}, TenantOrderPO{Name: "bulk2"}))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), rowsAffected)
+
+		data.ClearTenant()
+		lst := dbContext.Order.Where("name like ?", "bulk%").ToList()
+		assert.Equal(t, 2, lst.Count())
+		lst.Foreach(func(item *TenantOrderPO) {
+			assert.Equal(t, "t5", item.TenantId)
+		})
+	})
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/farseer-go/collections"
+	"github.com/farseer-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBeginTx(t *testing.T) {
+	var dbContext TestMysqlContext
+	data.InitContext(&dbContext, "test")
+
+	newUser := func(name string) *UserPO {
+		return &UserPO{Name: name, Age: 18, Specialty: collections.NewList("go"), Attribute: collections.NewDictionary[string, string]()}
+	}
+	defer func() {
+		_, _ = dbContext.User.Where("Name like ?", "tx_%").Delete()
+	}()
+
+	t.Run("跨协程提交", func(t *testing.T) {
+		tx, err := dbContext.BeginTx(context.Background(), nil)
+		assert.Nil(t, err)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, dbContext.User.InTx(tx).Insert(newUser("tx_commit")))
+		}()
+		wg.Wait()
+
+		// 提交前，事务外查不到
+		assert.False(t, dbContext.User.Where("Name = ?", "tx_commit").IsExists())
+		assert.True(t, dbContext.User.InTx(tx).Where("Name = ?", "tx_commit").IsExists())
+		assert.Nil(t, tx.Commit())
+		assert.True(t, dbContext.User.Where("Name = ?", "tx_commit").IsExists())
+	})
+
+	t.Run("回滚", func(t *testing.T) {
+		tx, err := dbContext.BeginTx(context.Background(), nil)
+		assert.Nil(t, err)
+		assert.Nil(t, dbContext.User.InTx(tx).Insert(newUser("tx_rollback")))
+		_, err = tx.ExecuteSql("update user set age = 20 where name = ?", "tx_rollback")
+		assert.Nil(t, err)
+		assert.Nil(t, tx.Rollback())
+		assert.False(t, dbContext.User.Where("Name = ?", "tx_rollback").IsExists())
+	})
+
+	t.Run("Returning按主键回查", func(t *testing.T) {
+		tx, err := dbContext.BeginTx(context.Background(), nil)
+		assert.Nil(t, err)
+		// mysql不支持RETURNING，回查需要在同一个事务中，才能查到未提交的记录
+		po := newUser("tx_returning")
+		assert.Nil(t, dbContext.User.InTx(tx).Returning().Insert(po))
+		assert.Equal(t, "tx_returning", po.Name)
+		assert.Equal(t, 18, po.Age)
+
+		lst, err := dbContext.User.InTx(tx).Where("Name = ?", "tx_returning").Returning().DeleteReturning()
+		assert.Nil(t, err)
+		assert.Equal(t, 1, lst.Count())
+		assert.Nil(t, tx.Rollback())
+	})
+}
+
+func TestOpenTransactions(t *testing.T) {
+	var dbContext TestMysqlContext
+	data.InitContext(&dbContext, "test")
+
+	tx, err := dbContext.BeginTx(context.Background(), nil)
+	assert.Nil(t, err)
+
+	var found *data.OpenTransaction
+	for _, openTransaction := range data.GetOpenTransactions() {
+		if openTransaction.Explicit && strings.Contains(openTransaction.CallSite, "tx_test.go") {
+			found = &openTransaction
+		}
+	}
+	assert.NotNil(t, found)
+	assert.Equal(t, "test", found.KeyName)
+
+	assert.Nil(t, tx.Rollback())
+	for _, openTransaction := range data.GetOpenTransactions() {
+		assert.NotContains(t, openTransaction.CallSite, "tx_test.go")
+	}
+}
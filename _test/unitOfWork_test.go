@@ -0,0 +1,50 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/farseer-go/collections"
+	"github.com/farseer-go/data"
+	"github.com/farseer-go/fs/exception"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitOfWork(t *testing.T) {
+	data.RegisterInternalContext("uow", "DataType=MySql,PoolMaxSize=5,PoolMinSize=1,ConnectionString=root:qwe123@tcp(192.168.1.8:3306)/farseer_test?charset=utf8&parseTime=True&loc=Local")
+	var testContext, uowContext TestMysqlContext
+	data.InitContext(&testContext, "test")
+	data.InitContext(&uowContext, "uow")
+
+	newUser := func(name string) *UserPO {
+		return &UserPO{Name: name, Age: 18, Specialty: collections.NewList("go"), Attribute: collections.NewDictionary[string, string]()}
+	}
+	defer func() {
+		_, _ = testContext.User.Where("Name like ?", "uow_%").Delete()
+	}()
+
+	t.Run("全部提交", func(t *testing.T) {
+		err := data.NewUnitOfWork().
+			Add("test", func() { _ = testContext.User.Insert(newUser("uow_test")) }).
+			Add("uow", func() { _ = uowContext.User.Insert(newUser("uow_uow")) }).
+			Execute()
+		assert.Nil(t, err)
+		assert.True(t, testContext.User.Where("Name = ?", "uow_test").IsExists())
+		assert.True(t, testContext.User.Where("Name = ?", "uow_uow").IsExists())
+	})
+
+	t.Run("任意失败全部回滚", func(t *testing.T) {
+		err := data.NewUnitOfWork().
+			Add("test", func() { _ = testContext.User.Insert(newUser("uow_test2")) }).
+			Add("uow", func() { exception.ThrowRefuseException("失败") }).
+			Execute()
+		assert.NotNil(t, err)
+		assert.False(t, testContext.User.Where("Name = ?", "uow_test2").IsExists())
+	})
+
+	t.Run("未注册的数据库", func(t *testing.T) {
+		err := data.NewUnitOfWork().Add("not_exists", func() {}).Execute()
+		assert.NotNil(t, err)
+		assert.False(t, errors.Is(err, data.ErrConnection))
+	})
+}
@@ -0,0 +1,307 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/farseer-go/collections"
+	"gorm.io/gorm/schema"
+)
+
+// bulkLoadProgressRows 批量导入时，每读取多少条记录汇报一次进度
+const bulkLoadProgressRows = 10000
+
+// ErrBulkLoadNotSupported 数据库拒绝原生的批量导入（如mysql未开启local_infile），由IBulkLoader返回
+var ErrBulkLoadNotSupported = errors.New("数据库不支持原生的批量导入")
+
+// bulkLoadBatchSize 驱动不支持原生导入时，每批InsertList的数量
+const bulkLoadBatchSize = 1000
+
+// 批量导入时解析PO的缓存
+var bulkSchemaCache = &sync.Map{}
+
+// CsvSource CSV格式的批量导入数据源，首行为字段名（数据库列名或PO字段名）
+type CsvSource struct {
+	Reader io.Reader
+	Comma  rune // 分隔符，默认为逗号
+}
+
+// NewCsvSource 创建CSV格式的批量导入数据源
+func NewCsvSource(reader io.Reader) CsvSource {
+	return CsvSource{Reader: reader, Comma: ','}
+}
+
+// JsonLinesSource JSON Lines格式的批量导入数据源，每行一个JSON对象，导入的字段以第一行为准
+type JsonLinesSource struct {
+	Reader io.Reader
+}
+
+// NewJsonLinesSource 创建JSON Lines格式的批量导入数据源
+func NewJsonLinesSource(reader io.Reader) JsonLinesSource {
+	return JsonLinesSource{Reader: reader}
+}
+
+// bulkRows 将不同的数据源统一转换成逐行读取
+type bulkRows[Table any] struct {
+	fields        []*schema.Field       // 要导入的字段
+	autoIncrement *schema.Field         // 导入了自增字段时，每一行都需要有值
+	next          func() (Table, error) // 读取下一行，读完时返回io.EOF
+	readCount     int64                 // 已读取的数量
+	progress      func(readCount int64) // 每读取bulkLoadProgressRows条回调一次
+}
+
+// 根据数据源创建逐行读取器
+// source支持：collections.List[Table]、[]Table、chan Table、CsvSource、JsonLinesSource
+func newBulkRows[Table any](source any) (*bulkRows[Table], error) {
+	tableSchema, err := schema.Parse(new(Table), bulkSchemaCache, schema.NamingStrategy{IdentifierMaxLength: 64})
+	if err != nil {
+		return nil, err
+	}
+	rows := &bulkRows[Table]{}
+
+	switch src := source.(type) {
+	case collections.List[Table]:
+		rows.next = arrayReader(src.ToArray())
+		err = rows.poFields(tableSchema)
+	case []Table:
+		rows.next = arrayReader(src)
+		err = rows.poFields(tableSchema)
+	case chan Table:
+		rows.next = chanReader[Table](src)
+		err = rows.poFields(tableSchema)
+	case <-chan Table:
+		rows.next = chanReader(src)
+		err = rows.poFields(tableSchema)
+	case CsvSource:
+		err = rows.csvReader(tableSchema, src)
+	case JsonLinesSource:
+		err = rows.jsonLinesReader(tableSchema, src)
+	default:
+		err = fmt.Errorf("批量导入不支持的数据源类型：%T", source)
+	}
+	return rows, err
+}
+
+// columns 要导入的数据库字段名
+func (receiver *bulkRows[Table]) columns() []string {
+	var columns []string
+	for _, field := range receiver.fields {
+		columns = append(columns, field.DBName)
+	}
+	return columns
+}
+
+// read 读取下一行，读完时返回io.EOF
+func (receiver *bulkRows[Table]) read() (Table, error) {
+	po, err := receiver.next()
+	if err == nil {
+		if receiver.readCount++; receiver.readCount%bulkLoadProgressRows == 0 && receiver.progress != nil {
+			receiver.progress(receiver.readCount)
+		}
+	}
+	return po, err
+}
+
+// values 读取下一行，并转换成与columns对应的数据库值，读完时返回io.EOF
+func (receiver *bulkRows[Table]) values() ([]any, error) {
+	po, err := receiver.read()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rv := reflect.ValueOf(&po).Elem()
+	values := make([]any, len(receiver.fields))
+	for i, field := range receiver.fields {
+		val, isZero := field.ValueOf(ctx, rv)
+		if isZero && field == receiver.autoIncrement {
+			return nil, fmt.Errorf("批量导入时，自增字段：%s 需要每一行都有值（第%d行为0），或者都不赋值", field.DBName, receiver.readCount)
+		}
+		// 自定义类型、json序列化字段，转成数据库能识别的值
+		if valuer, isValuer := val.(driver.Valuer); isValuer {
+			if val, err = valuer.Value(); err != nil {
+				return nil, fmt.Errorf("批量导入时，字段：%s 转换失败：%s", field.DBName, err.Error())
+			}
+		}
+		values[i] = val
+	}
+	return values, nil
+}
+
+// 读取CSV：首行为字段名，之后每行转换成PO
+func (receiver *bulkRows[Table]) csvReader(tableSchema *schema.Schema, src CsvSource) error {
+	reader := csv.NewReader(src.Reader)
+	if src.Comma != 0 {
+		reader.Comma = src.Comma
+	}
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("批量导入时，读取CSV字段名失败：%s", err.Error())
+	}
+	if receiver.fields, err = lookUpFields(tableSchema, header); err != nil {
+		return err
+	}
+
+	receiver.next = func() (Table, error) {
+		var po Table
+		record, err := reader.Read()
+		if err != nil {
+			return po, err
+		}
+		rv := reflect.ValueOf(&po).Elem()
+		for i, field := range receiver.fields {
+			// 空值保持PO字段的零值
+			if i >= len(record) || record[i] == "" {
+				continue
+			}
+			if err = field.Set(context.Background(), rv, record[i]); err != nil {
+				return po, fmt.Errorf("批量导入时，字段：%s 的值：%s 转换失败：%s", field.DBName, record[i], err.Error())
+			}
+		}
+		return po, nil
+	}
+	return nil
+}
+
+// 读取JSON Lines：每行一个JSON对象，导入的字段以第一行为准
+func (receiver *bulkRows[Table]) jsonLinesReader(tableSchema *schema.Schema, src JsonLinesSource) error {
+	scanner := bufio.NewScanner(src.Reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	// 读取下一行非空的JSON对象
+	readLine := func() (map[string]any, error) {
+		for scanner.Scan() {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			line := make(map[string]any)
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				return nil, fmt.Errorf("批量导入时，解析JSON失败：%s", err.Error())
+			}
+			return line, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	first, err := readLine()
+	if err != nil && err != io.EOF {
+		return err
+	}
+	var names []string
+	for name := range first {
+		names = append(names, name)
+	}
+	if receiver.fields, err = lookUpFields(tableSchema, names); err != nil {
+		return err
+	}
+
+	receiver.next = func() (Table, error) {
+		var po Table
+		line := first
+		if line != nil {
+			first = nil
+		} else if line, err = readLine(); err != nil {
+			return po, err
+		}
+
+		rv := reflect.ValueOf(&po).Elem()
+		for _, field := range receiver.fields {
+			val, exists := line[field.DBName]
+			if !exists {
+				val, exists = line[field.Name]
+			}
+			if !exists || val == nil {
+				continue
+			}
+			// 嵌套的对象、数组，还原成JSON字符串，交给字段自身的Scan、序列化器处理
+			switch val.(type) {
+			case map[string]any, []any:
+				marshal, _ := json.Marshal(val)
+				val = string(marshal)
+			}
+			if err := field.Set(context.Background(), rv, val); err != nil {
+				return po, fmt.Errorf("批量导入时，字段：%s 的值：%v 转换失败：%s", field.DBName, val, err.Error())
+			}
+		}
+		return po, nil
+	}
+	return nil
+}
+
+// PO数据源要导入的字段：与InsertList一致，第一行的自增字段有值时写入，否则由数据库生成
+func (receiver *bulkRows[Table]) poFields(tableSchema *schema.Schema) error {
+	// 预读第一行，之后再从第一行开始返回
+	first, err := receiver.next()
+	if err != nil && err != io.EOF {
+		return err
+	}
+	next := receiver.next
+	firstErr := err
+	receiver.next = func() (Table, error) {
+		receiver.next = next
+		return first, firstErr
+	}
+
+	rv := reflect.ValueOf(&first).Elem()
+	for _, field := range tableSchema.Fields {
+		if field.DBName == "" || !field.Creatable {
+			continue
+		}
+		if field.AutoIncrement {
+			if _, isZero := field.ValueOf(context.Background(), rv); firstErr != nil || isZero {
+				continue
+			}
+			receiver.autoIncrement = field
+		}
+		receiver.fields = append(receiver.fields, field)
+	}
+	return nil
+}
+
+// 根据数据库列名或PO字段名，找到对应的字段
+func lookUpFields(tableSchema *schema.Schema, names []string) ([]*schema.Field, error) {
+	var fields []*schema.Field
+	for _, name := range names {
+		field := tableSchema.LookUpField(name)
+		if field == nil || field.DBName == "" {
+			return nil, fmt.Errorf("批量导入时，PO：%s 中找不到字段：%s", tableSchema.Name, name)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// 逐行读取数组
+func arrayReader[Table any](arr []Table) func() (Table, error) {
+	index := 0
+	return func() (Table, error) {
+		if index >= len(arr) {
+			var po Table
+			return po, io.EOF
+		}
+		index++
+		return arr[index-1], nil
+	}
+}
+
+// 逐行读取通道，通道关闭时结束
+func chanReader[Table any](ch <-chan Table) func() (Table, error) {
+	return func() (Table, error) {
+		po, ok := <-ch
+		if !ok {
+			return po, io.EOF
+		}
+		return po, nil
+	}
+}
@@ -2,6 +2,7 @@ package data
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -12,46 +13,16 @@ import (
 // 确保实现了IConnectionChecker接口
 var _ core.IConnectionChecker = (*connectionChecker)(nil)
 
+// 连接检查默认的超时时间
+const defaultCheckTimeout = 10 * time.Second
+
 type connectionChecker struct{}
 
 // Check 检查连接字符串是否能成功连接到数据库
 // 实现IConnectionChecker接口
+// 使用临时的连接，检查完成后关闭，不会缓存连接池；需要分阶段的诊断报告时，使用Diagnose
 func (c *connectionChecker) Check(configString string) (bool, error) {
-	// 取消链路
-	trace.Manager().Ignore()
-
-	if configString == "" {
-		return false, fmt.Errorf("连接字符串不能为空")
-	}
-
-	// 使用NewInternalContext解析配置字符串并验证基础配置
-	internalCtx := NewInternalContext(configString)
-
-	if internalCtx.dbConfig.ConnectionString == "" {
-		return false, fmt.Errorf("连接字符串配置不正确：%s", configString)
-	}
-
-	if internalCtx.dbConfig.DataType == "" {
-		return false, fmt.Errorf("数据库类型配置不正确：%s", configString)
-	}
-
-	// 使用open函数建立数据库连接
-	gormDB, err := open(internalCtx.dbConfig)
-	if err != nil {
-		return false, err
-	}
-
-	// 获取数据库连接并进行ping测试
-	sqlDB, err := gormDB.DB()
-	if err != nil {
-		return false, fmt.Errorf("获取数据库连接失败[%s]：%s", internalCtx.dbConfig.DataType, err.Error())
-	}
-
-	if err := sqlDB.Ping(); err != nil {
-		return false, fmt.Errorf("Ping数据库失败[%s]：%s", internalCtx.dbConfig.DataType, err.Error())
-	}
-
-	return true, nil
+	return c.check(context.Background(), configString)
 }
 
 // CheckConnection 检查连接字符串是否能成功连接到数据库
@@ -66,27 +37,34 @@ func (c *connectionChecker) Check(configString string) (bool, error) {
 // timeout 为0时使用默认的10秒超时，参数类型为 time.Duration
 func (c *connectionChecker) CheckWithTimeout(configString string, timeout time.Duration) (bool, error) {
 	if timeout == 0 {
-		timeout = 10 * time.Second
+		timeout = defaultCheckTimeout
 	}
 
+	// 超时通过context取消连接，不再另起协程（超时后协程仍在运行，且连接不会关闭）
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	type result struct {
-		success bool
-		err     error
+	success, err := c.check(ctx, configString)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return false, fmt.Errorf("连接检查超时，超时时间：%v", timeout)
 	}
-	resultChan := make(chan result, 1)
+	return success, err
+}
+
+// 使用临时的连接检查，ctx取消时中止
+func (c *connectionChecker) check(ctx context.Context, configString string) (bool, error) {
+	// 取消链路
+	trace.Manager().Ignore()
 
-	go func() {
-		success, err := c.Check(configString)
-		resultChan <- result{success: success, err: err}
-	}()
+	config, err := parseCheckConfig(configString)
+	if err != nil {
+		return false, err
+	}
 
-	select {
-	case <-ctx.Done():
-		return false, fmt.Errorf("连接检查超时，超时时间：%v", timeout)
-	case res := <-resultChan:
-		return res.success, res.err
+	gormDB, err := openTemporary(ctx, config)
+	if err != nil {
+		return false, err
 	}
+	closeTemporary(gormDB)
+	return true, nil
 }
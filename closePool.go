@@ -0,0 +1,145 @@
+package data
+
+import (
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/farseer-go/fs/flog"
+	"gorm.io/gorm"
+)
+
+// 默认等待进行中的查询完成的最长时间（秒）
+const defaultDrainSeconds = 10
+
+// 等待进行中的查询完成的最长时间
+func (receiver *dbConfig) getDrainTimeout() time.Duration {
+	if receiver.DrainSeconds <= 0 {
+		return defaultDrainSeconds * time.Second
+	}
+	return time.Duration(receiver.DrainSeconds) * time.Second
+}
+
+// 同一个keyName的注册（切换连接池）串行执行
+var swapLocks = make(map[string]*sync.Mutex)
+
+// 获取keyName的注册锁
+func getSwapLock(keyName string) *sync.Mutex {
+	lock.Lock()
+	defer lock.Unlock()
+	swapLock, exists := swapLocks[keyName]
+	if !exists {
+		swapLock = &sync.Mutex{}
+		swapLocks[keyName] = swapLock
+	}
+	return swapLock
+}
+
+// 配置更新后替换连接池：使用新的配置创建连接池，旧的连接池等进行中的查询完成后关闭
+// 还未打开过连接池时，不需要处理，下次使用时会按新的配置打开
+// 创建新的连接池失败时返回错误，继续使用旧的连接池；调用方需持有getSwapLock
+func swapPool(dbConfig *dbConfig) error {
+	lock.Lock()
+	// 正在创建连接池时，等待创建完成后再替换
+	if pending, exists := pendingPools[dbConfig.keyName]; exists {
+		lock.Unlock()
+		<-pending.done
+		lock.Lock()
+	}
+	_, exists := databaseConn[dbConfig.keyName]
+	lock.Unlock()
+
+	// 先创建新的连接池，成功后再替换
+	var gormDB *gorm.DB
+	if exists {
+		var err error
+		if gormDB, err = createPool(dbConfig); err != nil {
+			return err
+		}
+	}
+
+	lock.Lock()
+	old, exists := databaseConn[dbConfig.keyName]
+	if gormDB != nil {
+		databaseConn[dbConfig.keyName] = &databasePool{gormDB: gormDB, dbConfig: dbConfig, lastUsed: time.Now()}
+	}
+	// 只读副本的连接池，下次使用时按新的配置打开
+	var replicas []*databasePool
+	for key, pool := range databaseConn {
+		if strings.HasPrefix(key, dbConfig.keyName+"#replica") {
+			replicas = append(replicas, pool)
+			delete(databaseConn, key)
+		}
+	}
+	lock.Unlock()
+	for _, replica := range replicas {
+		go drainPool(replica, dbConfig.getDrainTimeout())
+	}
+	if gormDB == nil {
+		return nil
+	}
+
+	warmPool(gormDB, dbConfig)
+	monitorFailover(dbConfig)
+	if exists {
+		go drainPool(old, dbConfig.getDrainTimeout())
+	}
+	return nil
+}
+
+// 等待连接池中进行中的查询、事务完成后关闭，超过timeout时强制关闭
+func drainPool(pool *databasePool, timeout time.Duration) {
+	sqlDB, err := pool.gormDB.DB()
+	if err != nil {
+		return
+	}
+	if inUse := waitIdle(sqlDB, timeout); inUse > 0 {
+		flog.Warningf("[config.yaml]Database.%s 关闭连接池时，等待%s后仍有%d个连接在使用中，强制关闭", pool.dbConfig.displayName(), timeout, inUse)
+	}
+	if err = sqlDB.Close(); err != nil {
+		flog.Warningf("[config.yaml]Database.%s 关闭连接池失败：%s", pool.dbConfig.displayName(), err.Error())
+	}
+}
+
+// 等待所有连接归还，返回超时后仍在使用中的连接数
+func waitIdle(sqlDB *sql.DB, timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	for {
+		inUse := sqlDB.Stats().InUse
+		if inUse == 0 || time.Now().After(deadline) {
+			return inUse
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// 关闭所有连接池（应用关闭时），停止连接池的定时检查，每个连接池最多等待DrainSeconds
+func closeAllPools() {
+	lock.Lock()
+	stopPoolMonitor()
+	pools := make([]*databasePool, 0, len(databaseConn))
+	for key, pool := range databaseConn {
+		pools = append(pools, pool)
+		delete(databaseConn, key)
+	}
+	lock.Unlock()
+
+	var wg sync.WaitGroup
+	for _, pool := range pools {
+		wg.Add(1)
+		go func(pool *databasePool) {
+			defer wg.Done()
+			drainPool(pool, pool.dbConfig.getDrainTimeout())
+		}(pool)
+	}
+	wg.Wait()
+}
+
+// 连接池是否仍在databaseConn中使用（被替换或关闭后返回false）
+func isActivePool(keyName string, gormDB *gorm.DB) bool {
+	lock.Lock()
+	defer lock.Unlock()
+	pool, exists := databaseConn[keyName]
+	return exists && pool.gormDB == gormDB
+}
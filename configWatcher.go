@@ -0,0 +1,134 @@
+package data
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/farseer-go/fs/configure"
+	"github.com/farseer-go/fs/flog"
+	"gopkg.in/yaml.v3"
+)
+
+// 配置文件的检查间隔（./config.yaml的Data.ConfigWatchSeconds），默认0：不检查
+func getConfigWatchInterval() time.Duration {
+	return time.Duration(configure.GetInt("Data.ConfigWatchSeconds")) * time.Second
+}
+
+// configWatcher 配置文件修改后，重新加载其中的Database节点
+// fs的配置只在启动时读取一次，因此直接读取配置文件；环境变量、配置中心中的Database配置不会重新读取
+type configWatcher struct {
+	file    string         // 配置文件路径（与configure.InitConfig相同）
+	modTime time.Time      // 上一次读取时的修改时间
+	nodes   map[string]any // 上一次读取到的Database节点
+}
+
+var (
+	configWatchStop chan struct{} // 配置文件检查的停止信号，未启动时为nil
+	configWatchLock sync.Mutex
+)
+
+// 开启了Data.ConfigWatchSeconds时，启动配置文件的检查
+func startConfigWatcher() {
+	interval := getConfigWatchInterval()
+	if interval <= 0 {
+		return
+	}
+
+	configWatchLock.Lock()
+	defer configWatchLock.Unlock()
+	if configWatchStop != nil {
+		return
+	}
+	watcher := newConfigWatcher(getConfigFile())
+	configWatchStop = make(chan struct{})
+	go watcher.watch(interval, configWatchStop)
+}
+
+// 停止配置文件的检查
+func stopConfigWatcher() {
+	configWatchLock.Lock()
+	defer configWatchLock.Unlock()
+	if configWatchStop != nil {
+		close(configWatchStop)
+		configWatchStop = nil
+	}
+}
+
+// 配置文件路径，与configure.InitConfig的规则相同
+func getConfigFile() string {
+	if fsEnv := os.Getenv("fsenv"); fsEnv != "" {
+		return fmt.Sprintf("./farseer.%s.yaml", fsEnv)
+	}
+	return "./farseer.yaml"
+}
+
+func newConfigWatcher(file string) *configWatcher {
+	watcher := &configWatcher{file: file}
+	if info, err := os.Stat(file); err == nil {
+		watcher.modTime = info.ModTime()
+	}
+	watcher.nodes, _ = readDatabaseNodes(file)
+	return watcher
+}
+
+// 定时检查，stop关闭时退出
+func (receiver *configWatcher) watch(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			receiver.check()
+		}
+	}
+}
+
+// 配置文件的修改时间有变化时，重新加载Database节点
+func (receiver *configWatcher) check() {
+	info, err := os.Stat(receiver.file)
+	if err != nil || info.ModTime().Equal(receiver.modTime) {
+		return
+	}
+	receiver.modTime = info.ModTime()
+
+	fileNodes, err := readDatabaseNodes(receiver.file)
+	if err != nil {
+		flog.Warningf("[%s]重新读取Database节点失败，继续使用旧的配置：%s", receiver.file, err.Error())
+		return
+	}
+
+	// 以内存中的配置为准（包括SetDefault、环境变量），配置文件中的节点覆盖，从配置文件中删除的节点移除
+	nodes := configure.GetSubNodes("Database")
+	for key := range receiver.nodes {
+		if _, exists := fileNodes[key]; !exists {
+			delete(nodes, key)
+		}
+	}
+	for key, node := range fileNodes {
+		nodes[key] = node
+	}
+	receiver.nodes = fileNodes
+	flog.Infof("[%s]配置文件已修改，重新加载Database节点", receiver.file)
+	applyDatabaseNodes(nodes)
+}
+
+// 读取配置文件中的Database节点
+func readDatabaseNodes(file string) (map[string]any, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err = yaml.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	nodes, _ := m["Database"].(map[string]any)
+	if nodes == nil {
+		nodes = make(map[string]any)
+	}
+	return nodes, nil
+}
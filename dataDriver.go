@@ -1,20 +1,61 @@
 package data
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/farseer-go/fs/parse"
+	"github.com/farseer-go/fs/sonyflake"
+	mysqlDriver "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type DataDriver struct {
 }
 
+// 内置的驱动实现了全部的可选接口
+var (
+	_ IInsertIgnorer           = (*DataDriver)(nil)
+	_ IErrorTranslator         = (*DataDriver)(nil)
+	_ IConnectionStringBuilder = (*DataDriver)(nil)
+	_ IConnectionStringParser  = (*DataDriver)(nil)
+)
+
 func (receiver *DataDriver) GetDriver(connectionString string) gorm.Dialector {
 	// user:123456@tcp(127.0.0.1:3306)/dbname?charset=utf8mb4&parseTime=True&loc=Local
 	return mysql.Open(connectionString)
 }
 
+// TLSConnector 设置mysql的Config.TLS（等同于mysql.RegisterTLSConfig后在连接字符串中指定tls=名称）
+func (receiver *DataDriver) TLSConnector(connectionString string, tlsConfig *tls.Config) (driver.Connector, error) {
+	config, err := mysqlDriver.ParseDSN(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		config.TLS = tlsConfig
+	}
+	return mysqlDriver.NewConnector(config)
+}
+
+func (receiver *DataDriver) GetDriverWithConn(conn gorm.ConnPool) gorm.Dialector {
+	return mysql.New(mysql.Config{Conn: conn})
+}
+
 func (receiver *DataDriver) CreateIndex(tableName string, idxName string, idxField IdxField) string {
 	var b bytes.Buffer
 	b.WriteString("CREATE ")
@@ -24,3 +65,186 @@ func (receiver *DataDriver) CreateIndex(tableName string, idxName string, idxFie
 	b.WriteString(fmt.Sprintf("INDEX %s ON %s (%s);", idxName, tableName, idxField.Fields))
 	return b.String()
 }
+
+func (receiver *DataDriver) InsertIgnore() []clause.Expression {
+	// INSERT IGNORE INTO ...
+	return []clause.Expression{clause.Insert{Modifier: "IGNORE"}}
+}
+
+// CreateConnectionString user:pass@tcp(127.0.0.1:3306)/dbname?charset=utf8mb4&parseTime=True&loc=Local
+func (receiver *DataDriver) CreateConnectionString(options DatabaseOptions) string {
+	config := mysqlDriver.NewConfig()
+	config.User = options.User
+	config.Passwd = options.Password
+	config.Net = "tcp"
+	config.Addr = options.Host
+	if options.Port > 0 {
+		config.Addr = net.JoinHostPort(options.Host, strconv.Itoa(options.Port))
+	}
+	config.DBName = options.Database
+	config.Params = options.MergeOptions(map[string]string{"charset": "utf8mb4", "parseTime": "True", "loc": "Local"})
+	return config.FormatDSN()
+}
+
+// ParseConnectionString 解析user:pass@tcp(127.0.0.1:3306)/dbname
+func (receiver *DataDriver) ParseConnectionString(connectionString string) (ConnectionInfo, error) {
+	config, err := mysqlDriver.ParseDSN(connectionString)
+	if err != nil {
+		return ConnectionInfo{}, err
+	}
+	info := ConnectionInfo{User: config.User, Host: config.Addr, Database: config.DBName, TLS: config.TLS != nil}
+	if host, port, err := net.SplitHostPort(config.Addr); err == nil {
+		info.Host = host
+		info.Port, _ = strconv.Atoi(port)
+	}
+	return info, nil
+}
+
+// TranslateError 将mysql的错误码转换成统一的错误类型
+func (receiver *DataDriver) TranslateError(err error) error {
+	if errors.Is(err, mysqlDriver.ErrInvalidConn) {
+		return ErrConnection
+	}
+	var mysqlErr *mysqlDriver.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return nil
+	}
+	switch mysqlErr.Number {
+	case 1062, 1586: // Duplicate entry
+		return ErrDuplicateKey
+	case 1213: // Deadlock found when trying to get lock
+		return ErrDeadlock
+	case 1205: // Lock wait timeout exceeded
+		return ErrLockTimeout
+	case 1216, 1217, 1451, 1452: // Cannot add or update a child row: a foreign key constraint fails
+		return ErrForeignKey
+	case 3024: // Query execution was interrupted, maximum statement execution time exceeded
+		return ErrTimeout
+	case 1040, 1053, 2006, 2013: // Too many connections、Server shutdown、MySQL server has gone away、Lost connection
+		return ErrConnection
+	}
+	return nil
+}
+
+// BulkLoad 使用LOAD DATA LOCAL INFILE导入，数据以TSV格式通过管道流式写入（服务端需开启local_infile）
+// 服务端未开启local_infile时（1148、3948），返回ErrBulkLoadNotSupported
+func (receiver *DataDriver) BulkLoad(sqlDB *sql.DB, tableName string, columns []string, nextRow func() ([]any, error)) (int64, error) {
+	readerName := fmt.Sprintf("data_bulk_%d", sonyflake.GenerateId())
+	pipeReader, pipeWriter := io.Pipe()
+	// 服务端请求数据时才开始读取：服务端拒绝导入时没有读取任何行，可以退化为分批写入
+	mysqlDriver.RegisterReaderHandler(readerName, func() io.Reader {
+		go writeMysqlTsv(pipeWriter, nextRow)
+		return pipeReader
+	})
+	defer mysqlDriver.DeregisterReaderHandler(readerName)
+
+	quotedColumns := make([]string, len(columns))
+	for i, column := range columns {
+		quotedColumns[i] = mysqlQuote(column)
+	}
+	query := fmt.Sprintf("LOAD DATA LOCAL INFILE 'Reader::%s' INTO TABLE %s CHARACTER SET utf8mb4 FIELDS TERMINATED BY '\\t' ESCAPED BY '\\\\' LINES TERMINATED BY '\\n' (%s)", readerName, mysqlQuote(tableName), strings.Join(quotedColumns, ","))
+	result, err := sqlDB.Exec(query)
+	// 导入失败时，关闭管道，让写入的协程退出
+	_ = pipeReader.Close()
+	if err != nil {
+		var mysqlErr *mysqlDriver.MySQLError
+		if errors.As(err, &mysqlErr) && (mysqlErr.Number == 1148 || mysqlErr.Number == 3948) { // The used command is not allowed、Loading local data is disabled
+			return 0, fmt.Errorf("%w：%s", ErrBulkLoadNotSupported, err.Error())
+		}
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// 边读取边以TSV格式写入管道
+func writeMysqlTsv(pipeWriter *io.PipeWriter, nextRow func() ([]any, error)) {
+	var err error
+	writer := bufio.NewWriter(pipeWriter)
+	for {
+		var values []any
+		if values, err = nextRow(); err != nil {
+			break
+		}
+		for i, val := range values {
+			if i > 0 {
+				_ = writer.WriteByte('\t')
+			}
+			_, _ = writer.WriteString(mysqlTsvValue(val))
+		}
+		if err = writer.WriteByte('\n'); err != nil {
+			break
+		}
+	}
+	if err == io.EOF {
+		err = writer.Flush()
+	}
+	_ = pipeWriter.CloseWithError(err)
+}
+
+// 表名、字段名加上反引号（库名.表名分别处理）
+func mysqlQuote(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = "`" + strings.ReplaceAll(part, "`", "``") + "`"
+	}
+	return strings.Join(parts, ".")
+}
+
+// LOAD DATA的转义字符
+var mysqlTsvReplacer = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r", "\x00", "\\0")
+
+// 转换成LOAD DATA能识别的TSV值
+func mysqlTsvValue(val any) string {
+	switch v := val.(type) {
+	case nil:
+		return "\\N"
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999")
+	case []byte:
+		return mysqlTsvReplacer.Replace(string(v))
+	default:
+		return mysqlTsvReplacer.Replace(parse.ToString(v))
+	}
+}
+
+// Lock 使用GET_LOCK获取锁，锁的持有者为当前连接（名称最长64个字符，超出时取哈希）
+func (receiver *DataDriver) Lock(conn *sql.Conn, name string, timeout time.Duration) (bool, error) {
+	var result sql.NullInt64
+	// GET_LOCK的超时以秒为单位，不足1秒的部分向上取整，避免被截断为0（不等待）
+	seconds := int64((timeout + time.Second - 1) / time.Second)
+	if err := conn.QueryRowContext(context.Background(), "SELECT GET_LOCK(?, ?)", mysqlLockName(name), seconds).Scan(&result); err != nil {
+		return false, err
+	}
+	return result.Valid && result.Int64 == 1, nil
+}
+
+// Unlock 使用RELEASE_LOCK释放锁
+func (receiver *DataDriver) Unlock(conn *sql.Conn, name string) error {
+	_, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", mysqlLockName(name))
+	return err
+}
+
+// GET_LOCK的名称最长64个字符
+func mysqlLockName(name string) string {
+	if len(name) <= 64 {
+		return name
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(name)))
+}
+
+// IsWritable 开启了read_only（或super_read_only）时为只读的从库
+func (receiver *DataDriver) IsWritable(ctx context.Context, sqlDB *sql.DB) (bool, error) {
+	var readOnly, superReadOnly sql.NullInt64
+	if err := sqlDB.QueryRowContext(ctx, "SELECT @@global.read_only, @@global.super_read_only").Scan(&readOnly, &superReadOnly); err != nil {
+		// MySQL 5.6及以下没有super_read_only
+		if err = sqlDB.QueryRowContext(ctx, "SELECT @@global.read_only").Scan(&readOnly); err != nil {
+			return false, err
+		}
+	}
+	return readOnly.Int64 == 0 && superReadOnly.Int64 == 0, nil
+}
@@ -0,0 +1,290 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/farseer-go/fs/parse"
+)
+
+// DatabaseOptions 结构化的数据库配置，对应./config.yaml中对象形式的Database节点：
+//
+//	Database:
+//	  default:
+//	    DataType: mysql
+//	    Host: 127.0.0.1
+//	    Port: 3306
+//	    User: root
+//	    Password: "pa,ss"
+//	    Database: dbname
+//	    Options: { charset: utf8mb4 }
+//	    Pool: { MaxSize: 20, MinSize: 1 }
+//	    Retry: { MaxAttempts: 3, Backoff: 100, On: [deadlock, connection] }
+//	    Replicas: [ { Host: 127.0.0.2 } ]
+//	    Hosts: [ 127.0.0.1:3306, 127.0.0.3:3306 ]  # 故障转移，第一个为主库
+//	    TargetSessionAttrs: read-write
+//	    TLS: { Mode: verify-full, CAFile: /etc/ssl/db-ca.pem }
+//
+// 连接字符串由各驱动的CreateConnectionString生成，密码等字段可以包含逗号
+type DatabaseOptions struct {
+	DataType         string            // 数据库类型：mysql、postgresql、sqlserver、clickhouse、sqlite
+	Host             string            // 主机
+	Port             int               // 端口，不设置时使用数据库的默认端口
+	User             string            // 用户名
+	Password         string            // 密码
+	Database         string            // 数据库名称（sqlite为数据库文件）
+	Options          map[string]string // 连接参数（如charset、sslmode），与驱动的默认参数合并，同名时以此为准
+	ConnectionString string            // 直接指定连接字符串，设置后忽略Host、Port、User、Password、Options
+	Migrate          string            // code first
+	Pool             PoolOptions       // 连接池
+	Retry            RetryOptions      // 重试策略
+	Replicas         []ReplicaOptions  // 只读副本，除Host、Port外，与主库使用相同的配置
+	Hosts            []HostOptions     // 故障转移的主机列表，第一个为主库，设置后忽略Host、Port
+	TxWarnSeconds    int               // 事务持续超过多少秒时提示，默认不提示
+	TxAbortSeconds   int               // 事务持续超过多少秒时强制回滚，默认不回滚
+	LockLeaseSeconds int               // 不支持原生锁的数据库，分布式锁的租约时长（秒），默认60，持有期间自动续期
+	DrainSeconds     int               // 配置热更新、应用关闭时，等待进行中的查询完成的最长时间（秒），默认10
+
+	// 故障转移
+	TargetSessionAttrs   string // 连接的会话要求：any（默认）、read-write（必须是可写的主库，与postgres的target_session_attrs相同）
+	FailoverCheckSeconds int    // 多个主机时，检查当前主机、尝试切回主库的间隔（秒），默认10
+
+	TLS TLSOptions // TLS，由驱动转换成原生的方式
+}
+
+// PoolOptions 连接池配置
+type PoolOptions struct {
+	MaxSize     int // 最大连接数，默认10
+	MinSize     int // 最小连接数，启动后预先创建，并定时补足
+	MaxIdle     int // 最大空闲连接数，默认等于MaxSize
+	MaxLifetime int // 连接的最长使用时间（秒），默认3600（clickhouse为600），小于0时不限制
+	MaxIdleTime int // 连接的最长空闲时间（秒），默认不限制（clickhouse为300），小于0时不限制
+	WaitWarnMs  int // 平均等待时间超过多少毫秒时提示（连接池饱和），默认100，小于0时不提示
+}
+
+// RetryOptions 重试策略配置
+type RetryOptions struct {
+	MaxAttempts int      // 最多执行的次数（包含第一次），默认不重试
+	Backoff     int      // 首次重试前的等待时间（毫秒），之后每次翻倍，默认100
+	On          []string // 需要重试的错误类型：deadlock、serialization、connection、locktimeout、timeout
+}
+
+// ReplicaOptions 只读副本配置
+type ReplicaOptions struct {
+	Host string // 主机
+	Port int    // 端口，不设置时与主库相同
+}
+
+// HostOptions 故障转移的主机配置
+type HostOptions struct {
+	Host string // 主机
+	Port int    // 端口，不设置时使用Port
+}
+
+// 解析对象形式的Database节点，字段名忽略大小写
+func parseDatabaseOptions(node map[string]any) DatabaseOptions {
+	options := DatabaseOptions{
+		DataType:         parse.ToString(getNodeValue(node, "DataType")),
+		Host:             parse.ToString(getNodeValue(node, "Host")),
+		Port:             parse.ToInt(getNodeValue(node, "Port")),
+		User:             parse.ToString(getNodeValue(node, "User")),
+		Password:         parse.ToString(getNodeValue(node, "Password")),
+		Database:         parse.ToString(getNodeValue(node, "Database")),
+		ConnectionString: parse.ToString(getNodeValue(node, "ConnectionString")),
+		Migrate:          parse.ToString(getNodeValue(node, "Migrate")),
+		TxWarnSeconds:    parse.ToInt(getNodeValue(node, "TxWarnSeconds")),
+		TxAbortSeconds:   parse.ToInt(getNodeValue(node, "TxAbortSeconds")),
+		LockLeaseSeconds: parse.ToInt(getNodeValue(node, "LockLeaseSeconds")),
+		DrainSeconds:     parse.ToInt(getNodeValue(node, "DrainSeconds")),
+		// 故障转移
+		TargetSessionAttrs:   parse.ToString(getNodeValue(node, "TargetSessionAttrs")),
+		FailoverCheckSeconds: parse.ToInt(getNodeValue(node, "FailoverCheckSeconds")),
+	}
+	if optionsNode, isMap := getNodeValue(node, "Options").(map[string]any); isMap {
+		options.Options = make(map[string]string, len(optionsNode))
+		for key, val := range optionsNode {
+			options.Options[key] = parse.ToString(val)
+		}
+	}
+
+	if poolNode, isMap := getNodeValue(node, "Pool").(map[string]any); isMap {
+		options.Pool.MaxSize = parse.ToInt(getNodeValue(poolNode, "MaxSize"))
+		options.Pool.MinSize = parse.ToInt(getNodeValue(poolNode, "MinSize"))
+		options.Pool.MaxIdle = parse.ToInt(getNodeValue(poolNode, "MaxIdle"))
+		options.Pool.MaxLifetime = parse.ToInt(getNodeValue(poolNode, "MaxLifetime"))
+		options.Pool.MaxIdleTime = parse.ToInt(getNodeValue(poolNode, "MaxIdleTime"))
+		options.Pool.WaitWarnMs = parse.ToInt(getNodeValue(poolNode, "WaitWarnMs"))
+	}
+
+	if retryNode, isMap := getNodeValue(node, "Retry").(map[string]any); isMap {
+		options.Retry.MaxAttempts = parse.ToInt(getNodeValue(retryNode, "MaxAttempts"))
+		options.Retry.Backoff = parse.ToInt(getNodeValue(retryNode, "Backoff"))
+		switch on := getNodeValue(retryNode, "On").(type) {
+		case []any:
+			for _, name := range on {
+				options.Retry.On = append(options.Retry.On, parse.ToString(name))
+			}
+		case string:
+			options.Retry.On = strings.Split(on, "|")
+		}
+	}
+
+	for _, replica := range parseHosts(getNodeValue(node, "Replicas")) {
+		options.Replicas = append(options.Replicas, ReplicaOptions(replica))
+	}
+	options.Hosts = parseHosts(getNodeValue(node, "Hosts"))
+
+	if tlsNode, isMap := getNodeValue(node, "TLS").(map[string]any); isMap {
+		options.TLS.Mode = parse.ToString(getNodeValue(tlsNode, "Mode"))
+		options.TLS.CAFile = parse.ToString(getNodeValue(tlsNode, "CAFile"))
+		options.TLS.CertFile = parse.ToString(getNodeValue(tlsNode, "CertFile"))
+		options.TLS.KeyFile = parse.ToString(getNodeValue(tlsNode, "KeyFile"))
+		options.TLS.ServerName = parse.ToString(getNodeValue(tlsNode, "ServerName"))
+	}
+	return options
+}
+
+// 解析主机列表，支持对象与host:port的简写形式
+func parseHosts(node any) []HostOptions {
+	hosts, isArray := node.([]any)
+	if !isArray {
+		return nil
+	}
+	var lst []HostOptions
+	for _, item := range hosts {
+		switch hostNode := item.(type) {
+		case map[string]any:
+			lst = append(lst, HostOptions{Host: parse.ToString(getNodeValue(hostNode, "Host")), Port: parse.ToInt(getNodeValue(hostNode, "Port"))})
+		case string:
+			// host:port 的简写形式
+			host, port, _ := strings.Cut(hostNode, ":")
+			lst = append(lst, HostOptions{Host: host, Port: parse.ToInt(port)})
+		}
+	}
+	return lst
+}
+
+// 忽略大小写获取节点的值
+func getNodeValue(node map[string]any, key string) any {
+	if val, exists := node[key]; exists {
+		return val
+	}
+	for nodeKey, val := range node {
+		if strings.EqualFold(nodeKey, key) {
+			return val
+		}
+	}
+	return nil
+}
+
+// 转换成数据库配置，由驱动生成连接字符串
+func (receiver DatabaseOptions) toDbConfig() (dbConfig, error) {
+	config := dbConfig{
+		DataType:         strings.ToLower(receiver.DataType),
+		PoolMaxSize:      receiver.Pool.MaxSize,
+		PoolMinSize:      receiver.Pool.MinSize,
+		PoolMaxIdle:      receiver.Pool.MaxIdle,
+		PoolMaxLifetime:  receiver.Pool.MaxLifetime,
+		PoolMaxIdleTime:  receiver.Pool.MaxIdleTime,
+		PoolWaitWarnMs:   receiver.Pool.WaitWarnMs,
+		ConnectionString: receiver.ConnectionString,
+		Migrate:          receiver.Migrate,
+		RetryMaxAttempts: receiver.Retry.MaxAttempts,
+		RetryBackoff:     receiver.Retry.Backoff,
+		RetryOn:          strings.Join(receiver.Retry.On, "|"),
+		TxWarnSeconds:    receiver.TxWarnSeconds,
+		TxAbortSeconds:   receiver.TxAbortSeconds,
+		LockLeaseSeconds: receiver.LockLeaseSeconds,
+		DrainSeconds:     receiver.DrainSeconds,
+		// 故障转移
+		TargetSessionAttrs:   strings.ToLower(receiver.TargetSessionAttrs),
+		FailoverCheckSeconds: receiver.FailoverCheckSeconds,
+		// TLS
+		TlsMode:       receiver.TLS.Mode,
+		TlsCaFile:     receiver.TLS.CAFile,
+		TlsCertFile:   receiver.TLS.CertFile,
+		TlsKeyFile:    receiver.TLS.KeyFile,
+		TlsServerName: receiver.TLS.ServerName,
+	}
+	if config.DataType == "" {
+		return config, fmt.Errorf("DataType，配置不正确")
+	}
+
+	// 直接指定了连接字符串
+	if config.ConnectionString != "" {
+		return config, nil
+	}
+	if receiver.Database == "" {
+		return config, fmt.Errorf("Database，配置不正确")
+	}
+
+	// 多个主机时，第一个为主库
+	if len(receiver.Hosts) > 0 {
+		receiver = receiver.host(0)
+	}
+	var err error
+	if config.ConnectionString, err = config.createConnectionString(receiver); err != nil {
+		return config, err
+	}
+	config.databaseName = receiver.Database
+	// 含有密钥占位符时，新建连接时再解析并生成连接字符串
+	if receiver.hasSecret() {
+		options := receiver
+		config.secretOptions = &options
+	}
+
+	// 故障转移的主机
+	if len(receiver.Hosts) > 1 {
+		for index := range receiver.Hosts {
+			connectionString, _ := config.createConnectionString(receiver.host(index))
+			config.hosts = append(config.hosts, connectionString)
+		}
+		config.failover = &failoverState{}
+	}
+
+	// 只读副本
+	for index := range receiver.Replicas {
+		connectionString, _ := config.createConnectionString(receiver.replica(index))
+		config.replicas = append(config.replicas, connectionString)
+	}
+	return config, nil
+}
+
+// 第index个只读副本的配置，除Host、Port外，与主库相同
+func (receiver DatabaseOptions) replica(index int) DatabaseOptions {
+	replica := receiver.Replicas[index]
+	receiver.Host = replica.Host
+	if replica.Port > 0 {
+		receiver.Port = replica.Port
+	}
+	receiver.Replicas = nil
+	return receiver
+}
+
+// 第index个故障转移主机的配置，除Host、Port外，与主库相同
+func (receiver DatabaseOptions) host(index int) DatabaseOptions {
+	host := receiver.Hosts[index]
+	receiver.Host = host.Host
+	if host.Port > 0 {
+		receiver.Port = host.Port
+	}
+	return receiver
+}
+
+// MergeOptions 合并驱动的默认连接参数与配置的连接参数，同名时以配置为准（供驱动生成连接字符串时使用）
+func (receiver DatabaseOptions) MergeOptions(defaults map[string]string) map[string]string {
+	merged := make(map[string]string, len(defaults)+len(receiver.Options))
+	for key, val := range defaults {
+		merged[key] = val
+	}
+	for key, val := range receiver.Options {
+		// 忽略大小写覆盖默认参数（如TimeZone与timezone）
+		for defKey := range defaults {
+			if strings.EqualFold(defKey, key) {
+				delete(merged, defKey)
+			}
+		}
+		merged[key] = val
+	}
+	return merged
+}
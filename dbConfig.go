@@ -5,23 +5,114 @@ import (
 
 	"github.com/farseer-go/fs/container"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // 数据库配置
 type dbConfig struct {
 	keyName          string // 用于共享事务和共享连接池，如果不设置此值，每次都会重新创建连接
 	DataType         string
-	PoolMaxSize      int
-	PoolMinSize      int
+	PoolMaxSize      int // 最大连接数，默认10
+	PoolMinSize      int // 最小连接数，启动后预先创建，并定时补足，默认0
+	PoolMaxIdle      int // 最大空闲连接数，默认等于PoolMaxSize
+	PoolMaxLifetime  int // 连接的最长使用时间（秒），默认3600（clickhouse为600），小于0时不限制
+	PoolMaxIdleTime  int // 连接的最长空闲时间（秒），默认不限制（clickhouse为300），小于0时不限制
+	PoolWaitWarnMs   int // 连接池平均等待时间超过多少毫秒时提示（连接池饱和），默认100，小于0时不提示
 	ConnectionString string
-	databaseName     string // 数据库名称
-	Migrate          string // code first
+	databaseName     string           // 数据库名称
+	replicas         []string         // 只读副本的连接字符串
+	hosts            []string         // 故障转移的主机的连接字符串，第一个为主库
+	failover         *failoverState   // 故障转移的状态（当前使用的主机）
+	dynamic          bool             // 动态连接（未通过RegisterInternalContext注册），keyName为连接字符串
+	redacted         string           // 脱敏后的连接字符串（user@host:port/db），连接字符串需要输出到模块外时使用
+	secretOptions    *DatabaseOptions // 结构化配置中含有密钥占位符时，保留原始配置，新建连接时重新解析
+	Migrate          string           // code first
+	RetryMaxAttempts int              // 遇到可重试的错误时，最多执行的次数（包含第一次），默认不重试
+	RetryBackoff     int              // 首次重试前的等待时间（毫秒），之后每次翻倍，默认100
+	TxWarnSeconds    int              // 事务持续超过多少秒时提示（可能忘记了Commit或Rollback），默认不提示
+	TxAbortSeconds   int              // 事务持续超过多少秒时强制回滚，默认不回滚
+	LockLeaseSeconds int              // 不支持原生锁的数据库（sqlite、clickhouse），分布式锁的租约时长（秒），默认60，持有期间自动续期
+	DrainSeconds     int              // 配置热更新、应用关闭时，等待进行中的查询完成后再关闭连接池的最长时间（秒），默认10
+	RetryOn          string           // 需要重试的错误类型，用|分隔：deadlock|serialization|connection|locktimeout|timeout，默认deadlock|serialization|connection
+
+	// 故障转移
+	TargetSessionAttrs   string // 连接的会话要求：any（默认）、read-write（必须是可写的主库，只读的从库视为不可用）
+	FailoverCheckSeconds int    // 多个主机时，检查当前主机、尝试切回主库的间隔（秒），默认10
+
+	// TLS，由驱动转换成原生的方式，不需要在连接字符串中设置
+	TlsMode       string // 校验方式：disable（默认）、require、verify-ca、verify-full
+	TlsCaFile     string // CA证书文件，不设置时使用系统的根证书
+	TlsCertFile   string // 客户端证书文件（双向认证）
+	TlsKeyFile    string // 客户端私钥文件
+	TlsServerName string // 校验证书的主机名，默认为连接的主机
+}
+
+// GetDriver 获取对应驱动（连接字符串中的密钥占位符已替换成密钥）
+func (receiver *dbConfig) GetDriver() (gorm.Dialector, error) {
+	connectionString, err := receiver.resolveConnectionString()
+	if err != nil {
+		return nil, err
+	}
+	if receiver.getTLSOptions().enabled() {
+		return receiver.getTLSDriver(connectionString)
+	}
+	return receiver.GetDataDriver().GetDriver(connectionString), nil
+}
+
+// 将驱动的原生错误转换成统一的错误类型
+func (receiver *dbConfig) translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return translateError(receiver.GetDataDriver(), err)
+}
+
+// 用于日志、错误信息的名称：动态连接的keyName是连接字符串，使用脱敏后的连接字符串代替
+func (receiver *dbConfig) displayName() string {
+	if receiver.dynamic {
+		return receiver.redacted
+	}
+	return receiver.keyName
 }
 
-// GetDriver 获取对应驱动
-func (receiver *dbConfig) GetDriver() gorm.Dialector {
+// 是否支持事务
+func (receiver *dbConfig) supportTransaction() bool {
+	switch receiver.DataType {
+	case "clickhouse":
+		return false
+	default:
+		return true
+	}
+}
+
+// GetDataDriver 获取对应的驱动模块
+func (receiver *dbConfig) GetDataDriver() IDataDriver {
 	if !container.IsRegister[IDataDriver](receiver.DataType) {
 		panic(fmt.Sprintf("要使用%s，请加载模块：对应的驱动，通常位置在：github.com/farseer-go/data/driver/%s", receiver.DataType, receiver.DataType))
 	}
-	return container.Resolve[IDataDriver](receiver.DataType).GetDriver(receiver.ConnectionString)
+	return container.Resolve[IDataDriver](receiver.DataType)
+}
+
+// 新增记录时忽略主键、唯一键冲突的子句，驱动未实现IInsertIgnorer时使用gorm通用的OnConflict
+func (receiver *dbConfig) getInsertIgnore() []clause.Expression {
+	if insertIgnorer, isInsertIgnorer := receiver.GetDataDriver().(IInsertIgnorer); isInsertIgnorer {
+		return insertIgnorer.InsertIgnore()
+	}
+	return []clause.Expression{clause.OnConflict{DoNothing: true}}
+}
+
+// 解析连接字符串，驱动未实现IConnectionStringParser时返回空的连接信息
+func (receiver *dbConfig) parseConnectionString(connectionString string) (ConnectionInfo, error) {
+	if parser, isParser := receiver.GetDataDriver().(IConnectionStringParser); isParser {
+		return parser.ParseConnectionString(connectionString)
+	}
+	return ConnectionInfo{}, nil
+}
+
+// 由驱动根据结构化的配置生成连接字符串，驱动未实现IConnectionStringBuilder时返回错误
+func (receiver *dbConfig) createConnectionString(options DatabaseOptions) (string, error) {
+	if builder, isBuilder := receiver.GetDataDriver().(IConnectionStringBuilder); isBuilder {
+		return builder.CreateConnectionString(options), nil
+	}
+	return "", fmt.Errorf("%s的驱动不支持结构化的配置，请使用ConnectionString", receiver.DataType)
 }
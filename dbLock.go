@@ -0,0 +1,234 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/farseer-go/fs/flog"
+	"github.com/farseer-go/fs/sonyflake"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lockTableName 系统表名称：不支持原生锁的数据库（sqlite、clickhouse），使用带过期时间的租约实现分布式锁
+const lockTableName = "data_lock"
+
+// 租约锁未获取到时，重新尝试的间隔
+const lockRetryInterval = 100 * time.Millisecond
+
+// 租约锁默认的过期时间（秒）
+const defaultLockLeaseSeconds = 60
+
+// lockPO 系统表 data_lock 的映射
+type lockPO struct {
+	Name     string    `gorm:"column:name;type:varchar(128);primaryKey"` // 锁名称
+	Owner    string    `gorm:"column:owner;type:varchar(64);primaryKey"` // 持有者
+	CreateAt time.Time `gorm:"column:create_at"`                         // 申请时间，最早申请且未过期的持有者获得锁
+	ExpireAt time.Time `gorm:"column:expire_at"`                         // 过期时间，持有者异常退出时，过期后自动释放
+}
+
+// TableName 指定系统表名
+func (lockPO) TableName() string {
+	return lockTableName
+}
+
+// DbLock 数据库分布式锁，通过Unlock释放
+type DbLock struct {
+	name     string
+	conn     *sql.Conn     // 持有原生锁的专用连接（锁与连接绑定，释放前不会归还连接池）
+	locker   ILocker       // 原生锁的实现
+	db       *gorm.DB      // 租约锁使用的数据库连接
+	owner    string        // 租约锁的持有者
+	stop     chan struct{} // 释放时关闭，停止租约锁的续期
+	released bool          // 是否已释放
+	lock     sync.Mutex    // 防止并发释放
+	dbConfig *dbConfig     // 数据库配置
+}
+
+// Name 锁名称
+func (receiver *DbLock) Name() string {
+	return receiver.name
+}
+
+// Unlock 释放锁，重复释放时直接返回
+func (receiver *DbLock) Unlock() error {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	if receiver.released {
+		return nil
+	}
+	receiver.released = true
+
+	// 原生锁：释放后归还连接
+	if receiver.conn != nil {
+		err := receiver.locker.Unlock(receiver.conn, receiver.name)
+		if err != nil {
+			// 释放失败时锁可能仍在这个连接上，丢弃连接（数据库在连接断开时释放锁），而不是归还连接池
+			_ = receiver.conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		_ = receiver.conn.Close()
+		return receiver.dbConfig.translateError(err)
+	}
+	// 租约锁：停止续期，删除自己的租约
+	close(receiver.stop)
+	return receiver.dbConfig.translateError(receiver.db.Where("name = ? and owner = ?", receiver.name, receiver.owner).Delete(&lockPO{}).Error)
+}
+
+// Lock 获取分布式锁，最多等待timeout，超时返回ErrLockTimeout
+func (receiver *internalContext) Lock(name string, timeout time.Duration) (*DbLock, error) {
+	lock, acquired, err := receiver.lock(name, timeout)
+	if err == nil && !acquired {
+		err = &dbError{kind: ErrLockTimeout, err: fmt.Errorf("获取锁：%s 超时（%s）", name, timeout)}
+	}
+	return lock, err
+}
+
+// TryLock 尝试获取分布式锁，不等待，acquired表示是否获取到
+func (receiver *internalContext) TryLock(name string) (lock *DbLock, acquired bool, err error) {
+	return receiver.lock(name, 0)
+}
+
+// 获取锁：驱动实现了ILocker时使用数据库原生的锁，否则使用租约锁
+func (receiver *internalContext) lock(name string, timeout time.Duration) (*DbLock, bool, error) {
+	if locker, isLocker := receiver.dbConfig.GetDataDriver().(ILocker); isLocker {
+		return receiver.nativeLock(locker, name, timeout)
+	}
+	return receiver.leaseLock(name, timeout)
+}
+
+// 数据库原生的锁，锁与专用连接绑定
+func (receiver *internalContext) nativeLock(locker ILocker, name string, timeout time.Duration) (*DbLock, bool, error) {
+	gormDB, err := openPool(receiver.dbConfig)
+	if err != nil {
+		return nil, false, err
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return nil, false, err
+	}
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		return nil, false, receiver.dbConfig.translateError(err)
+	}
+
+	acquired, err := locker.Lock(conn, name, timeout)
+	if err != nil || !acquired {
+		_ = conn.Close()
+		return nil, false, receiver.dbConfig.translateError(err)
+	}
+	return &DbLock{name: name, conn: conn, locker: locker, dbConfig: receiver.dbConfig}, true, nil
+}
+
+// 租约锁：写入自己的租约后，最早申请且未过期的持有者获得锁
+func (receiver *internalContext) leaseLock(name string, timeout time.Duration) (*DbLock, bool, error) {
+	if err := receiver.ensureLockTable(); err != nil {
+		return nil, false, err
+	}
+	gormDB, err := openPool(receiver.dbConfig)
+	if err != nil {
+		return nil, false, err
+	}
+	db := gormDB.Session(&gorm.Session{}).Table(lockTableName)
+
+	leaseSeconds := receiver.dbConfig.LockLeaseSeconds
+	if leaseSeconds <= 0 {
+		leaseSeconds = defaultLockLeaseSeconds
+	}
+	owner := fmt.Sprintf("%d", sonyflake.GenerateId())
+	deadline := time.Now().Add(timeout)
+	for {
+		now := time.Now()
+		po := lockPO{Name: name, Owner: owner, CreateAt: now, ExpireAt: now.Add(time.Duration(leaseSeconds) * time.Second)}
+		if err = db.Create(&po).Error; err != nil {
+			return nil, false, err
+		}
+
+		var first lockPO
+		if err = db.Where("name = ? and expire_at > ?", name, now).Order(clause.OrderBy{Columns: []clause.OrderByColumn{{Column: clause.Column{Name: "create_at"}}, {Column: clause.Column{Name: "owner"}}}}).Limit(1).Find(&first).Error; err != nil {
+			return nil, false, err
+		}
+		if first.Owner == owner {
+			lock := &DbLock{name: name, db: db, owner: owner, stop: make(chan struct{}), dbConfig: receiver.dbConfig}
+			go lock.keepalive(po, time.Duration(leaseSeconds)*time.Second)
+			return lock, true, nil
+		}
+
+		// 未获取到，撤回自己的租约，并清理已过期的租约
+		db.Where("name = ? and (owner = ? or expire_at <= ?)", name, owner, now).Delete(&lockPO{})
+		if !time.Now().Add(lockRetryInterval).Before(deadline) {
+			return nil, false, nil
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// keepalive 持有租约锁期间，每隔1/3的租约时间延长一次过期时间，直到Unlock
+func (receiver *DbLock) keepalive(po lockPO, lease time.Duration) {
+	ticker := time.NewTicker(lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-receiver.stop:
+			return
+		case <-ticker.C:
+		}
+
+		var err error
+		po.ExpireAt = time.Now().Add(lease)
+		if receiver.dbConfig.DataType == "clickhouse" {
+			// clickhouse不支持UPDATE，追加一条相同申请时间的租约（Unlock时按owner一并删除）
+			err = receiver.db.Create(&po).Error
+		} else {
+			result := receiver.db.Where("name = ? and owner = ?", po.Name, po.Owner).Update("expire_at", po.ExpireAt)
+			if err = result.Error; err == nil && result.RowsAffected == 0 {
+				flog.Warningf("租约锁：%s 已过期被清理，续期失败", po.Name)
+				return
+			}
+		}
+		if err != nil {
+			flog.Warningf("租约锁：%s 续期失败：%s", po.Name, err.Error())
+		}
+	}
+}
+
+// 每个上下文是否已创建系统表
+var (
+	lockTableLoaded = make(map[string]bool)
+	lockTableLock   sync.Mutex
+)
+
+// ensureLockTable 确保系统表存在，每个keyName只真正执行一次
+func (receiver *internalContext) ensureLockTable() error {
+	lockTableLock.Lock()
+	defer lockTableLock.Unlock()
+
+	if lockTableLoaded[receiver.dbConfig.keyName] {
+		return nil
+	}
+
+	db, err := openPool(receiver.dbConfig)
+	if err != nil {
+		return err
+	}
+	db = db.Session(&gorm.Session{})
+	if !db.Migrator().HasTable(lockTableName) {
+		if receiver.dbConfig.DataType == "clickhouse" {
+			ddl := "CREATE TABLE IF NOT EXISTS " + lockTableName + " (" +
+				"name String, owner String, create_at DateTime64(3), expire_at DateTime64(3)" +
+				") ENGINE = MergeTree ORDER BY (name, owner)"
+			err = db.Exec(ddl).Error
+		} else {
+			err = db.AutoMigrate(&lockPO{})
+		}
+		if err != nil {
+			flog.Warningf("创建系统表%s失败：%s", lockTableName, err.Error())
+			return err
+		}
+	}
+	lockTableLoaded[receiver.dbConfig.keyName] = true
+	return nil
+}
@@ -11,26 +11,44 @@ type DefaultRepository[TPoType any, TDomainObject any] struct {
 	primaryName        []string
 	table              TableSet[TPoType]
 	getInternalContext IGetInternalContext
+	tx                 *Tx // 显式的事务
 }
 
 func NewDefaultRepository[TPoType any, TDomainObject any](table TableSet[TPoType], getInternalContext IGetInternalContext) IRepository[TDomainObject] {
+	return NewDefaultTryRepository[TPoType, TDomainObject](table, getInternalContext)
+}
+
+func NewDefaultTryRepository[TPoType any, TDomainObject any](table TableSet[TPoType], getInternalContext IGetInternalContext) ITryRepository[TDomainObject] {
 	return &DefaultRepository[TPoType, TDomainObject]{primaryName: table.primaryName, table: table, getInternalContext: getInternalContext}
 }
 
+// InTx 返回在显式的事务中执行的仓储
+func (receiver *DefaultRepository[TPoType, TDomainObject]) InTx(tx *Tx) ITryRepository[TDomainObject] {
+	return &DefaultRepository[TPoType, TDomainObject]{primaryName: receiver.primaryName, table: receiver.table, getInternalContext: receiver.getInternalContext, tx: tx}
+}
+
+func (receiver *DefaultRepository[TPoType, TDomainObject]) getTableSet() *TableSet[TPoType] {
+	ts := receiver.table.setDbContext(receiver.getInternalContext)
+	if receiver.tx != nil {
+		return ts.InTx(receiver.tx)
+	}
+	return ts
+}
+
 func (receiver *DefaultRepository[TPoType, TDomainObject]) ToEntity(id any) TDomainObject {
-	po := receiver.table.setDbContext(receiver.getInternalContext).Where(receiver.primaryName[0], id).ToEntity()
+	po := receiver.getTableSet().Where(receiver.primaryName[0], id).ToEntity()
 	// po 转 do
 	return mapper.Single[TDomainObject](&po)
 }
 
 func (receiver *DefaultRepository[TPoType, TDomainObject]) Add(entity TDomainObject) error {
 	po := mapper.Single[TPoType](&entity)
-	return receiver.table.setDbContext(receiver.getInternalContext).Insert(&po)
+	return receiver.getTableSet().Insert(&po)
 }
 
 func (receiver *DefaultRepository[TPoType, TDomainObject]) AddIgnore(entity TDomainObject) error {
 	po := mapper.Single[TPoType](&entity)
-	_, err := receiver.table.setDbContext(receiver.getInternalContext).InsertIgnore(&po)
+	_, err := receiver.getTableSet().InsertIgnore(&po)
 	return err
 }
 
@@ -42,7 +60,7 @@ func (receiver *DefaultRepository[TPoType, TDomainObject]) AddList(lst collectio
 	lst.Select(&lstPO, func(entity TDomainObject) any {
 		return mapper.Single[TPoType](&entity)
 	})
-	return receiver.table.setDbContext(receiver.getInternalContext).InsertList(lstPO, batchSize)
+	return receiver.getTableSet().InsertList(lstPO, batchSize)
 }
 
 func (receiver *DefaultRepository[TPoType, TDomainObject]) AddIgnoreList(lst collections.List[TDomainObject], batchSize int) (int64, error) {
@@ -50,19 +68,19 @@ func (receiver *DefaultRepository[TPoType, TDomainObject]) AddIgnoreList(lst col
 	lst.Select(&lstPO, func(entity TDomainObject) any {
 		return mapper.Single[TPoType](&entity)
 	})
-	return receiver.table.setDbContext(receiver.getInternalContext).InsertIgnoreList(lstPO, batchSize)
+	return receiver.getTableSet().InsertIgnoreList(lstPO, batchSize)
 }
 
 func (receiver *DefaultRepository[TPoType, TDomainObject]) ToList() collections.List[TDomainObject] {
 	// 从数据库读数据
-	lstProduct := receiver.table.setDbContext(receiver.getInternalContext).ToList()
+	lstProduct := receiver.getTableSet().ToList()
 	// po 转 do
 	return mapper.ToList[TDomainObject](lstProduct)
 }
 
 func (receiver *DefaultRepository[TPoType, TDomainObject]) ToPageList(pageSize, pageIndex int) collections.PageList[TDomainObject] {
 	// 从数据库读数据
-	ts := receiver.table.setDbContext(receiver.getInternalContext)
+	ts := receiver.getTableSet()
 	for _, fieldName := range receiver.primaryName {
 		ts.Desc(fieldName)
 	}
@@ -73,21 +91,63 @@ func (receiver *DefaultRepository[TPoType, TDomainObject]) ToPageList(pageSize,
 }
 
 func (receiver *DefaultRepository[TPoType, TDomainObject]) Count() int64 {
-	count := receiver.table.setDbContext(receiver.getInternalContext).Count()
+	count := receiver.getTableSet().Count()
 	return count
 }
 
 func (receiver *DefaultRepository[TPoType, TDomainObject]) Update(id any, do TDomainObject) (int64, error) {
 	po := mapper.Single[TPoType](do)
-	return receiver.table.setDbContext(receiver.getInternalContext).Where(receiver.primaryName[0], id).Update(po)
+	return receiver.getTableSet().Where(receiver.primaryName[0], id).Update(po)
 }
 
 func (receiver *DefaultRepository[TPoType, TDomainObject]) Delete(id any) (int64, error) {
-	return receiver.table.setDbContext(receiver.getInternalContext).Where(receiver.primaryName[0], id).Delete()
+	return receiver.getTableSet().Where(receiver.primaryName[0], id).Delete()
 }
 
 func (receiver *DefaultRepository[TPoType, TDomainObject]) IsExists(id any) bool {
-	return receiver.table.setDbContext(receiver.getInternalContext).Where(receiver.primaryName[0], id).IsExists()
+	return receiver.getTableSet().Where(receiver.primaryName[0], id).IsExists()
+}
+
+func (receiver *DefaultRepository[TPoType, TDomainObject]) TryToEntity(id any) (TDomainObject, bool, error) {
+	po, found, err := receiver.getTableSet().Where(receiver.primaryName[0], id).FindOne()
+	if !found {
+		var do TDomainObject
+		return do, false, err
+	}
+	// po 转 do
+	return mapper.Single[TDomainObject](&po), true, err
+}
+
+func (receiver *DefaultRepository[TPoType, TDomainObject]) TryToList() (collections.List[TDomainObject], error) {
+	// 从数据库读数据
+	lstPO, err := receiver.getTableSet().TryToList()
+	if err != nil {
+		return collections.NewList[TDomainObject](), err
+	}
+	// po 转 do
+	return mapper.ToList[TDomainObject](lstPO), nil
+}
+
+func (receiver *DefaultRepository[TPoType, TDomainObject]) TryToPageList(pageSize, pageIndex int) (collections.PageList[TDomainObject], error) {
+	// 从数据库读数据
+	ts := receiver.getTableSet()
+	for _, fieldName := range receiver.primaryName {
+		ts.Desc(fieldName)
+	}
+	lstPO, err := ts.TryToPageList(pageSize, pageIndex)
+	if err != nil {
+		return collections.NewPageList(collections.NewList[TDomainObject](), 0), err
+	}
+	// po 转 do
+	return mapper.ToPageList[TDomainObject](lstPO), nil
+}
+
+func (receiver *DefaultRepository[TPoType, TDomainObject]) TryCount() (int64, error) {
+	return receiver.getTableSet().TryCount()
+}
+
+func (receiver *DefaultRepository[TPoType, TDomainObject]) TryIsExists(id any) (bool, error) {
+	return receiver.getTableSet().Where(receiver.primaryName[0], id).TryIsExists()
 }
 
 func (receiver *DefaultRepository[TPoType, TDomainObject]) Now() (time.Time, error) {
@@ -0,0 +1,396 @@
+package data
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/farseer-go/data/loggers"
+	"github.com/farseer-go/fs/container"
+	"github.com/farseer-go/fs/sonyflake"
+	"github.com/farseer-go/fs/trace"
+	"gorm.io/gorm"
+)
+
+// DiagnosticStatus 诊断阶段的结果
+type DiagnosticStatus string
+
+const (
+	DiagnosticPass DiagnosticStatus = "pass" // 通过
+	DiagnosticFail DiagnosticStatus = "fail" // 失败
+	DiagnosticSkip DiagnosticStatus = "skip" // 跳过（不适用，或前置阶段失败）
+)
+
+// 诊断的各个阶段
+const (
+	StageParse    = "parse"    // 解析连接字符串
+	StageDns      = "dns"      // 解析域名
+	StageTcp      = "tcp"      // TCP连接
+	StageTls      = "tls"      // TLS握手
+	StageAuth     = "auth"     // 身份验证
+	StageDatabase = "database" // 数据库是否存在
+	StageRead     = "read"     // 读权限
+	StageWrite    = "write"    // 写权限（创建、写入、删除临时表）
+	StageVersion  = "version"  // 数据库版本
+)
+
+// 各数据库的默认端口
+var defaultPorts = map[string]int{"mysql": 3306, "postgresql": 5432, "postgres": 5432, "sqlserver": 1433, "mssql": 1433, "clickhouse": 9000}
+
+// DiagnosticStage 诊断阶段的结果
+type DiagnosticStage struct {
+	Name    string           // 阶段名称
+	Status  DiagnosticStatus // 结果
+	Message string           // 说明（失败时为错误信息）
+	Elapsed time.Duration    // 耗时
+}
+
+// DiagnosticReport 连接诊断的报告
+type DiagnosticReport struct {
+	Success       bool              // 所有阶段都通过（或跳过）
+	DataType      string            // 数据库类型
+	Connection    string            // 脱敏后的连接信息：user@host:port/db
+	ServerVersion string            // 数据库版本
+	Stages        []DiagnosticStage // 各阶段的结果
+	Elapsed       time.Duration     // 总耗时
+}
+
+// String 逐行输出各阶段的结果
+func (receiver DiagnosticReport) String() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("[%s]%s success=%t elapsed=%s\n", receiver.DataType, receiver.Connection, receiver.Success, receiver.Elapsed.Round(time.Millisecond)))
+	for _, stage := range receiver.Stages {
+		b.WriteString(fmt.Sprintf("  %-8s %-4s %6s %s\n", stage.Name, stage.Status, stage.Elapsed.Round(time.Millisecond), stage.Message))
+	}
+	return b.String()
+}
+
+// FailedStage 第一个失败的阶段，全部通过时返回nil
+func (receiver DiagnosticReport) FailedStage() *DiagnosticStage {
+	for index := range receiver.Stages {
+		if receiver.Stages[index].Status == DiagnosticFail {
+			return &receiver.Stages[index]
+		}
+	}
+	return nil
+}
+
+// 诊断的过程
+type diagnostic struct {
+	report DiagnosticReport
+	failed bool // 已有阶段失败，后续阶段跳过
+}
+
+// 执行一个阶段：前置阶段失败时跳过；fn返回错误时为失败，之后的阶段都跳过
+func (receiver *diagnostic) run(name string, fn func() (DiagnosticStatus, string, error)) {
+	if receiver.failed {
+		receiver.report.Stages = append(receiver.report.Stages, DiagnosticStage{Name: name, Status: DiagnosticSkip, Message: "前置检查未通过"})
+		return
+	}
+	startAt := time.Now()
+	status, message, err := fn()
+	if err != nil {
+		status, message = DiagnosticFail, err.Error()
+		receiver.failed = true
+	}
+	receiver.report.Stages = append(receiver.report.Stages, DiagnosticStage{Name: name, Status: status, Message: message, Elapsed: time.Since(startAt)})
+}
+
+// 执行一个只依赖ready的阶段：不受之前阶段失败的影响（如只读用户写权限失败，仍然获取版本）
+func (receiver *diagnostic) runWhen(ready bool, name string, fn func() (DiagnosticStatus, string, error)) {
+	failed := receiver.failed
+	receiver.failed = !ready
+	receiver.run(name, fn)
+	receiver.failed = failed || (ready && receiver.failed)
+}
+
+// Diagnose 分阶段诊断连接字符串：解析、DNS、TCP、TLS、身份验证、数据库、读权限、写权限、版本
+// 写权限会在数据库中创建一张临时表并删除；诊断使用临时的连接，结束后关闭，不会缓存连接池
+// timeout为0时使用默认的10秒超时
+func Diagnose(configString string, timeout time.Duration) DiagnosticReport {
+	// 取消链路
+	trace.Manager().Ignore()
+	if timeout == 0 {
+		timeout = defaultCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	startAt := time.Now()
+	d := &diagnostic{}
+
+	// 解析连接字符串
+	var config *dbConfig
+	var info ConnectionInfo
+	d.run(StageParse, func() (DiagnosticStatus, string, error) {
+		var err error
+		if config, err = parseCheckConfig(configString); err != nil {
+			return "", "", err
+		}
+		d.report.DataType = config.DataType
+		d.report.Connection = config.redacted
+		if info, err = config.parseConnectionString(config.ConnectionString); err != nil {
+			return "", "", fmt.Errorf("解析连接字符串失败：%w", err)
+		}
+		if _, err = config.resolveConnectionString(); err != nil {
+			return "", "", err
+		}
+		return DiagnosticPass, "", nil
+	})
+
+	// sqlite、unix socket没有网络连接
+	network := config != nil && config.DataType != "sqlite" && info.Host != "" && !strings.HasPrefix(info.Host, "/")
+	port := info.Port
+	if port == 0 && config != nil {
+		port = defaultPorts[config.DataType]
+	}
+
+	d.run(StageDns, func() (DiagnosticStatus, string, error) {
+		if !network {
+			return DiagnosticSkip, "不需要网络连接", nil
+		}
+		if net.ParseIP(info.Host) != nil {
+			return DiagnosticSkip, "主机为IP地址", nil
+		}
+		addrs, err := net.DefaultResolver.LookupHost(ctx, info.Host)
+		if err != nil {
+			return "", "", fmt.Errorf("解析域名%s失败：%w", info.Host, err)
+		}
+		return DiagnosticPass, strings.Join(addrs, ","), nil
+	})
+
+	d.run(StageTcp, func() (DiagnosticStatus, string, error) {
+		if !network {
+			return DiagnosticSkip, "不需要网络连接", nil
+		}
+		address := net.JoinHostPort(info.Host, strconv.Itoa(port))
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", address)
+		if err != nil {
+			return "", "", fmt.Errorf("连接%s失败：%w", address, err)
+		}
+		_ = conn.Close()
+		return DiagnosticPass, address, nil
+	})
+
+	// TLS在驱动的协议中协商（如mysql、postgres先发送明文的请求），与身份验证一起完成，按错误类型区分
+	var gormDB *gorm.DB
+	var openErr error
+	if !d.failed {
+		gormDB, openErr = openTemporary(ctx, config)
+		if gormDB != nil {
+			defer closeTemporary(gormDB)
+		}
+	}
+	d.run(StageTls, func() (DiagnosticStatus, string, error) {
+		if !info.TLS && !config.getTLSOptions().enabled() {
+			return DiagnosticSkip, "未启用TLS", nil
+		}
+		if openErr != nil && isTlsError(openErr) {
+			return "", "", openErr
+		}
+		return DiagnosticPass, "", nil
+	})
+
+	unknownDatabase := openErr != nil && isUnknownDatabase(openErr)
+	d.run(StageAuth, func() (DiagnosticStatus, string, error) {
+		// 数据库不存在时，说明身份验证已通过
+		if openErr != nil && !unknownDatabase {
+			return "", "", openErr
+		}
+		return DiagnosticPass, info.User, nil
+	})
+
+	d.run(StageDatabase, func() (DiagnosticStatus, string, error) {
+		if openErr != nil {
+			return "", "", openErr
+		}
+		return DiagnosticPass, info.Database, nil
+	})
+
+	d.run(StageRead, func() (DiagnosticStatus, string, error) {
+		tables, err := gormDB.WithContext(ctx).Migrator().GetTables()
+		if err != nil {
+			return "", "", fmt.Errorf("获取表失败：%w", err)
+		}
+		if len(tables) == 0 {
+			return DiagnosticSkip, "数据库中没有表", nil
+		}
+		var rows []map[string]any
+		if err = gormDB.WithContext(ctx).Table(tables[0]).Limit(1).Find(&rows).Error; err != nil {
+			return "", "", fmt.Errorf("读取表%s失败：%w", tables[0], err)
+		}
+		return DiagnosticPass, tables[0], nil
+	})
+
+	d.run(StageWrite, func() (DiagnosticStatus, string, error) {
+		return DiagnosticPass, "", checkWritePermission(ctx, gormDB, config.DataType)
+	})
+
+	// 身份验证通过、连接可用时都获取版本，与读写权限的结果无关
+	d.runWhen(gormDB != nil && openErr == nil, StageVersion, func() (DiagnosticStatus, string, error) {
+		version, err := getServerVersion(ctx, gormDB, config.DataType)
+		if err != nil {
+			return "", "", err
+		}
+		d.report.ServerVersion = version
+		return DiagnosticPass, version, nil
+	})
+
+	d.report.Success = !d.failed
+	d.report.Elapsed = time.Since(startAt)
+	return d.report
+}
+
+// 解析并校验检查用的配置字符串
+func parseCheckConfig(configString string) (*dbConfig, error) {
+	if configString == "" {
+		return nil, fmt.Errorf("连接字符串不能为空")
+	}
+
+	// 使用NewInternalContext解析配置字符串并验证基础配置
+	config := NewInternalContext(configString).dbConfig
+	if config.ConnectionString == "" {
+		return nil, fmt.Errorf("连接字符串配置不正确")
+	}
+	if config.DataType == "" {
+		return nil, fmt.Errorf("数据库类型配置不正确：%s", config.redacted)
+	}
+	if !container.IsRegister[IDataDriver](config.DataType) {
+		return nil, fmt.Errorf("要使用%s，请加载模块：对应的驱动，通常位置在：github.com/farseer-go/data/driver/%s", config.DataType, config.DataType)
+	}
+	return config, nil
+}
+
+// 打开临时的连接（不放入databaseConn），使用完后需要调用closeTemporary关闭
+// gorm.Open初始化时会使用context.Background查询版本（如mysql的SELECT VERSION()），无法被超时取消
+// 因此驱动实现了ITLSDriver时，先使用原生的连接器在ctx内Ping成功（连接、身份验证），再交给gorm
+func openTemporary(ctx context.Context, config *dbConfig) (*gorm.DB, error) {
+	connDriver, isConnDriver := config.GetDataDriver().(ITLSDriver)
+	if !isConnDriver {
+		return openTemporaryDialector(ctx, config)
+	}
+	connectionString, err := config.resolveConnectionString()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := config.buildTLSConfig(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	connector, err := connDriver.TLSConnector(connectionString, tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败[%s]：%w", config.DataType, err)
+	}
+	sqlDB := sql.OpenDB(connector)
+	sqlDB.SetMaxOpenConns(1)
+	if err = sqlDB.PingContext(ctx); err != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("Ping数据库失败[%s]：%w", config.DataType, err)
+	}
+	gormDB, err := gorm.Open(connDriver.GetDriverWithConn(sqlDB), &gorm.Config{SkipDefaultTransaction: true, DisableAutomaticPing: true, Logger: loggers.NewFsLogger()})
+	if err != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("打开数据库失败[%s]：%w", config.DataType, err)
+	}
+	return gormDB, nil
+}
+
+// 驱动没有原生的连接器时（如sqlite，没有网络连接），由gorm打开
+func openTemporaryDialector(ctx context.Context, config *dbConfig) (*gorm.DB, error) {
+	dialector, err := config.GetDriver()
+	if err != nil {
+		return nil, err
+	}
+	// 不自动Ping，由PingContext控制超时
+	gormDB, err := gorm.Open(dialector, &gorm.Config{SkipDefaultTransaction: true, DisableAutomaticPing: true, Logger: loggers.NewFsLogger()})
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败[%s]：%w", config.DataType, err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		closeTemporary(gormDB)
+		return nil, fmt.Errorf("获取数据库连接失败[%s]：%w", config.DataType, err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err = sqlDB.PingContext(ctx); err != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("Ping数据库失败[%s]：%w", config.DataType, err)
+	}
+	return gormDB, nil
+}
+
+// 关闭临时的连接
+func closeTemporary(gormDB *gorm.DB) {
+	closeDB(gormDB)
+}
+
+// 是否为TLS握手、证书校验的错误
+func isTlsError(err error) bool {
+	var recordErr tls.RecordHeaderError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certErr x509.CertificateInvalidError
+	if errors.As(err, &recordErr) || errors.As(err, &unknownAuthority) || errors.As(err, &hostnameErr) || errors.As(err, &certErr) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "tls") || strings.Contains(msg, "x509") || strings.Contains(msg, "ssl")
+}
+
+// 是否为数据库不存在的错误（mysql 1049、postgres 3D000、sqlserver 4060、clickhouse 81）
+func isUnknownDatabase(err error) bool {
+	msg := err.Error()
+	for _, keyword := range []string{"Error 1049", "Unknown database", "SQLSTATE 3D000", "Cannot open database", "UNKNOWN_DATABASE", "code: 81,"} {
+		if strings.Contains(msg, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// 创建临时表、写入、删除，检查写权限
+func checkWritePermission(ctx context.Context, gormDB *gorm.DB, dataType string) error {
+	db := gormDB.WithContext(ctx)
+	tableName := db.Statement.Quote(fmt.Sprintf("data_diagnose_%d", sonyflake.GenerateId()))
+	ddl := "CREATE TABLE " + tableName + " (id int)"
+	if dataType == "clickhouse" {
+		ddl += " ENGINE = Memory"
+	}
+	if err := db.Exec(ddl).Error; err != nil {
+		return fmt.Errorf("创建表失败：%w", err)
+	}
+	// 使用新的context删除，超时后也不残留临时表
+	defer gormDB.WithContext(context.Background()).Exec("DROP TABLE " + tableName)
+
+	if err := db.Exec("INSERT INTO " + tableName + " (id) VALUES (1)").Error; err != nil {
+		return fmt.Errorf("写入表失败：%w", err)
+	}
+	return nil
+}
+
+// 获取数据库版本
+func getServerVersion(ctx context.Context, gormDB *gorm.DB, dataType string) (string, error) {
+	var sql string
+	switch dataType {
+	case "postgresql", "postgres":
+		sql = "SHOW server_version"
+	case "sqlserver", "mssql":
+		sql = "SELECT @@VERSION"
+	case "sqlite":
+		sql = "SELECT sqlite_version()"
+	default:
+		sql = "SELECT version()"
+	}
+	var version string
+	if err := gormDB.WithContext(ctx).Raw(sql).Scan(&version).Error; err != nil {
+		return "", fmt.Errorf("获取数据库版本失败：%w", err)
+	}
+	return strings.TrimSpace(version), nil
+}
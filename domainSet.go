@@ -17,4 +17,9 @@ func (r *DomainSet[TPo, TDomainObject]) Init(dbContext *internalContext, param m
 			return NewDefaultRepository[TPo, TDomainObject](r.TableSet, getInternalContext)
 		})
 	}
+	if !container.IsRegister[ITryRepository[TDomainObject]]() {
+		container.Register(func() ITryRepository[TDomainObject] {
+			return NewDefaultTryRepository[TPo, TDomainObject](r.TableSet, getInternalContext)
+		})
+	}
 }
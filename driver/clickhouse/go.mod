@@ -3,6 +3,7 @@ module github.com/farseer-go/data/driver/clickhouse
 go 1.24.0
 
 require (
+	github.com/ClickHouse/clickhouse-go/v2 v2.42.0
 	github.com/farseer-go/data v0.17.3
 	github.com/farseer-go/fs v0.17.3
 	gorm.io/driver/clickhouse v0.7.0
@@ -12,7 +13,6 @@ require (
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
 	github.com/ClickHouse/ch-go v0.69.0 // indirect
-	github.com/ClickHouse/clickhouse-go/v2 v2.42.0 // indirect
 	github.com/andybalholm/brotli v1.2.0 // indirect
 	github.com/cespare/xxhash/v2 v2.3.0 // indirect
 	github.com/farseer-go/collections v0.17.3 // indirect
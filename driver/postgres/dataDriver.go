@@ -2,20 +2,67 @@ package data_postgres
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/farseer-go/data"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type dataDriver struct {
 }
 
+// 内置的驱动实现了全部的可选接口
+var (
+	_ data.IInsertIgnorer           = (*dataDriver)(nil)
+	_ data.IErrorTranslator         = (*dataDriver)(nil)
+	_ data.IConnectionStringBuilder = (*dataDriver)(nil)
+	_ data.IConnectionStringParser  = (*dataDriver)(nil)
+)
+
 func (receiver *dataDriver) GetDriver(connectionString string) gorm.Dialector {
 	// host=127.0.0.1 user=user password=123456 dbname=dbname port=9920 sslmode=disable TimeZone=Asia/Shanghai
 	return postgres.Open(connectionString)
 }
 
+// TLSConnector 设置pgx的TLSConfig（替代连接字符串中的sslmode、sslrootcert等）
+func (receiver *dataDriver) TLSConnector(connectionString string, tlsConfig *tls.Config) (driver.Connector, error) {
+	config, err := pgx.ParseConfig(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		config.TLSConfig = tlsConfig
+		// sslmode=prefer时，会生成不使用TLS的备用配置；多个主机时，每个主机都使用TLS
+		fallbacks := config.Fallbacks[:0]
+		for _, fallback := range config.Fallbacks {
+			if fallback.TLSConfig != nil {
+				fallback.TLSConfig = tlsConfig
+				fallbacks = append(fallbacks, fallback)
+			}
+		}
+		config.Fallbacks = fallbacks
+	}
+	return stdlib.GetConnector(*config), nil
+}
+
+func (receiver *dataDriver) GetDriverWithConn(conn gorm.ConnPool) gorm.Dialector {
+	return postgres.New(postgres.Config{Conn: conn})
+}
+
 func (receiver *dataDriver) CreateIndex(tableName string, idxName string, idxField data.IdxField) string {
 	var b bytes.Buffer
 	b.WriteString("CREATE ")
@@ -25,3 +72,162 @@ func (receiver *dataDriver) CreateIndex(tableName string, idxName string, idxFie
 	b.WriteString(fmt.Sprintf("INDEX %s ON %s (%s);", idxName, tableName, idxField.Fields))
 	return b.String()
 }
+
+func (receiver *dataDriver) InsertIgnore() []clause.Expression {
+	// INSERT INTO ... ON CONFLICT DO NOTHING
+	return []clause.Expression{clause.OnConflict{DoNothing: true}}
+}
+
+// CreateConnectionString host=127.0.0.1 user=user password=123456 dbname=dbname port=5432 sslmode=disable TimeZone=Asia/Shanghai
+func (receiver *dataDriver) CreateConnectionString(options data.DatabaseOptions) string {
+	port := options.Port
+	if port == 0 {
+		port = 5432
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d", quoteDsnValue(options.Host), quoteDsnValue(options.User), quoteDsnValue(options.Password), quoteDsnValue(options.Database), port))
+
+	params := options.MergeOptions(map[string]string{"sslmode": "disable", "TimeZone": "Asia/Shanghai"})
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.WriteString(fmt.Sprintf(" %s=%s", key, quoteDsnValue(params[key])))
+	}
+	return b.String()
+}
+
+// 值为空或包含空格、引号、反斜杠时，使用单引号包裹并转义
+func quoteDsnValue(val string) string {
+	if val != "" && !strings.ContainsAny(val, " '\\") {
+		return val
+	}
+	return "'" + strings.NewReplacer("\\", "\\\\", "'", "\\'").Replace(val) + "'"
+}
+
+// ParseConnectionString 解析host=127.0.0.1 user=user password=123456 dbname=dbname port=5432，或postgres://的URL格式
+func (receiver *dataDriver) ParseConnectionString(connectionString string) (data.ConnectionInfo, error) {
+	config, err := pgconn.ParseConfig(connectionString)
+	if err != nil {
+		return data.ConnectionInfo{}, err
+	}
+	return data.ConnectionInfo{User: config.User, Host: config.Host, Port: int(config.Port), Database: config.Database, TLS: config.TLSConfig != nil}, nil
+}
+
+// TranslateError 将postgres的SQLSTATE转换成统一的错误类型
+func (receiver *dataDriver) TranslateError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return nil
+	}
+	switch pgErr.Code {
+	case "23505": // unique_violation
+		return data.ErrDuplicateKey
+	case "40P01": // deadlock_detected
+		return data.ErrDeadlock
+	case "55P03": // lock_not_available
+		return data.ErrLockTimeout
+	case "40001": // serialization_failure
+		return data.ErrSerialization
+	case "23503": // foreign_key_violation
+		return data.ErrForeignKey
+	case "57014": // query_canceled（statement_timeout）
+		return data.ErrTimeout
+	case "57P01", "57P02", "57P03": // admin_shutdown、crash_shutdown、cannot_connect_now
+		return data.ErrConnection
+	}
+	// Class 08 — Connection Exception
+	if strings.HasPrefix(pgErr.Code, "08") {
+		return data.ErrConnection
+	}
+	return nil
+}
+
+// BulkLoad 使用COPY FROM STDIN导入
+func (receiver *dataDriver) BulkLoad(sqlDB *sql.DB, tableName string, columns []string, nextRow func() ([]any, error)) (int64, error) {
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	var rowsAffected int64
+	err = conn.Raw(func(driverConn any) error {
+		stdConn, isPgx := driverConn.(*stdlib.Conn)
+		if !isPgx {
+			return fmt.Errorf("COPY FROM STDIN需要使用pgx驱动，当前驱动：%T", driverConn)
+		}
+		// 支持 schema.table 的写法
+		rowsAffected, err = stdConn.Conn().CopyFrom(ctx, strings.Split(tableName, "."), columns, &copyFromSource{nextRow: nextRow})
+		return err
+	})
+	return rowsAffected, err
+}
+
+// copyFromSource 将逐行读取转换成pgx.CopyFromSource
+type copyFromSource struct {
+	nextRow func() ([]any, error)
+	values  []any
+	err     error
+}
+
+var _ pgx.CopyFromSource = (*copyFromSource)(nil)
+
+func (receiver *copyFromSource) Next() bool {
+	receiver.values, receiver.err = receiver.nextRow()
+	return receiver.err == nil
+}
+
+func (receiver *copyFromSource) Values() ([]any, error) {
+	return receiver.values, nil
+}
+
+func (receiver *copyFromSource) Err() error {
+	if receiver.err == io.EOF {
+		return nil
+	}
+	return receiver.err
+}
+
+// Lock 使用会话级的advisory lock获取锁，锁的持有者为当前连接
+func (receiver *dataDriver) Lock(conn *sql.Conn, name string, timeout time.Duration) (bool, error) {
+	ctx := context.Background()
+	// 不等待
+	if timeout <= 0 {
+		var acquired bool
+		err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtextextended($1, 0))", name).Scan(&acquired)
+		return acquired, err
+	}
+
+	// 通过lock_timeout控制最长等待时间，超时时返回55P03
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET lock_timeout = %d", timeout.Milliseconds())); err != nil {
+		return false, err
+	}
+	defer func() { _, _ = conn.ExecContext(ctx, "RESET lock_timeout") }()
+
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock(hashtextextended($1, 0))", name)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "55P03" {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Unlock 释放advisory lock
+func (receiver *dataDriver) Unlock(conn *sql.Conn, name string) error {
+	_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtextextended($1, 0))", name)
+	return err
+}
+
+// IsWritable 处于恢复模式（standby）或默认只读事务时为只读，与libpq的target_session_attrs=read-write一致
+func (receiver *dataDriver) IsWritable(ctx context.Context, sqlDB *sql.DB) (bool, error) {
+	var inRecovery bool
+	var readOnly string
+	if err := sqlDB.QueryRowContext(ctx, "SELECT pg_is_in_recovery(), current_setting('default_transaction_read_only')").Scan(&inRecovery, &readOnly); err != nil {
+		return false, err
+	}
+	return !inRecovery && readOnly != "on", nil
+}
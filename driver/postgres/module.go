@@ -13,7 +13,8 @@ func (module Module) DependsModule() []modules.FarseerModule {
 	return []modules.FarseerModule{data.Module{}}
 }
 
-func (module Module) Initialize() {
+// PreInitialize 驱动需要在data.Module.Initialize解析Database配置之前注册
+func (module Module) PreInitialize() {
 	container.Register(func() data.IDataDriver {
 		return &dataDriver{}
 	}, "postgres")
@@ -2,15 +2,29 @@ package data_sqlite
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
+
 	"github.com/farseer-go/data"
+	"github.com/mattn/go-sqlite3"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type dataDriver struct {
 }
 
+// 内置的驱动实现了全部的可选接口
+var (
+	_ data.IInsertIgnorer           = (*dataDriver)(nil)
+	_ data.IErrorTranslator         = (*dataDriver)(nil)
+	_ data.IConnectionStringBuilder = (*dataDriver)(nil)
+	_ data.IConnectionStringParser  = (*dataDriver)(nil)
+)
+
 func (receiver *dataDriver) GetDriver(connectionString string) gorm.Dialector {
 	// gorm.db
 	return sqlite.Open(connectionString)
@@ -25,3 +39,45 @@ func (receiver *dataDriver) CreateIndex(tableName string, idxName string, idxFie
 	b.WriteString(fmt.Sprintf("INDEX %s ON %s (%s);", idxName, tableName, idxField.Fields))
 	return b.String()
 }
+
+func (receiver *dataDriver) InsertIgnore() []clause.Expression {
+	// INSERT INTO ... ON CONFLICT DO NOTHING
+	return []clause.Expression{clause.OnConflict{DoNothing: true}}
+}
+
+// CreateConnectionString gorm.db?_busy_timeout=5000
+func (receiver *dataDriver) CreateConnectionString(options data.DatabaseOptions) string {
+	if len(options.Options) == 0 {
+		return options.Database
+	}
+	query := url.Values{}
+	for key, val := range options.Options {
+		query.Set(key, val)
+	}
+	return options.Database + "?" + query.Encode()
+}
+
+// ParseConnectionString 解析gorm.db?_busy_timeout=5000，只保留数据库文件
+func (receiver *dataDriver) ParseConnectionString(connectionString string) (data.ConnectionInfo, error) {
+	database, _, _ := strings.Cut(strings.TrimPrefix(connectionString, "file:"), "?")
+	return data.ConnectionInfo{Database: database}, nil
+}
+
+// TranslateError 将sqlite的错误码转换成统一的错误类型
+func (receiver *dataDriver) TranslateError(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return nil
+	}
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		return data.ErrDuplicateKey
+	case sqlite3.ErrConstraintForeignKey:
+		return data.ErrForeignKey
+	}
+	switch sqliteErr.Code {
+	case sqlite3.ErrBusy, sqlite3.ErrLocked: // 数据库文件或表被锁定
+		return data.ErrLockTimeout
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ go 1.24.0
 require (
 	github.com/farseer-go/data v0.17.3
 	github.com/farseer-go/fs v0.17.3
+	github.com/mattn/go-sqlite3 v1.14.33
 	gorm.io/driver/sqlite v1.6.0
 	gorm.io/gorm v1.31.1
 )
@@ -18,7 +19,6 @@ require (
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
-	github.com/mattn/go-sqlite3 v1.14.33 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/timandy/routine v1.1.6 // indirect
@@ -0,0 +1,31 @@
+package data
+
+import (
+	"gorm.io/gorm"
+)
+
+// ErrorPlugin 执行SQL后，将驱动的原生错误转换成统一的错误类型（ErrDuplicateKey、ErrDeadlock等）
+type ErrorPlugin struct {
+	dataDriver IDataDriver
+}
+
+func (op *ErrorPlugin) Name() string {
+	return "errorPlugin"
+}
+
+func (op *ErrorPlugin) Initialize(db *gorm.DB) (err error) {
+	_ = db.Callback().Raw().After("gorm:raw").Register("translate_error", op.translate)
+	_ = db.Callback().Create().After("gorm:after_create").Register("translate_error", op.translate)
+	_ = db.Callback().Delete().After("gorm:after_delete").Register("translate_error", op.translate)
+	_ = db.Callback().Update().After("gorm:after_update").Register("translate_error", op.translate)
+	_ = db.Callback().Query().After("gorm:after_query").Register("translate_error", op.translate)
+	_ = db.Callback().Row().After("gorm:row").Register("translate_error", op.translate)
+	return
+}
+
+// 转换错误类型
+func (op *ErrorPlugin) translate(db *gorm.DB) {
+	if db.Error != nil {
+		db.Error = translateError(op.dataDriver, db.Error)
+	}
+}
@@ -0,0 +1,92 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// 统一的数据库错误类型，由各驱动将原生的错误码转换而来，可使用errors.Is判断
+var (
+	ErrDuplicateKey  = errors.New("主键或唯一键重复")
+	ErrDeadlock      = errors.New("死锁")
+	ErrLockTimeout   = errors.New("等待锁超时")
+	ErrSerialization = errors.New("事务序列化失败")
+	ErrConnection    = errors.New("数据库连接异常")
+	ErrForeignKey    = errors.New("违反外键约束")
+	ErrTimeout       = errors.New("执行超时")
+)
+
+// dbError 包装驱动的原生错误，错误信息保持不变
+// errors.Is可以判断统一的错误类型，errors.As仍然可以取到驱动的原生错误
+type dbError struct {
+	kind error // 统一的错误类型
+	err  error // 驱动的原生错误
+}
+
+func (receiver *dbError) Error() string {
+	return receiver.err.Error()
+}
+
+func (receiver *dbError) Unwrap() error {
+	return receiver.err
+}
+
+func (receiver *dbError) Is(target error) bool {
+	return receiver.kind == target
+}
+
+// 将驱动的原生错误转换成统一的错误类型，无法识别时原样返回
+func translateError(dataDriver IDataDriver, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// 已经转换过
+	var translated *dbError
+	if errors.As(err, &translated) {
+		return err
+	}
+
+	var kind error
+	if translator, isTranslator := dataDriver.(IErrorTranslator); isTranslator {
+		kind = translator.TranslateError(err)
+	}
+	if kind == nil {
+		kind = translateCommonError(err)
+	}
+	if kind == nil {
+		return err
+	}
+	return &dbError{kind: kind, err: err}
+}
+
+// 与驱动无关的错误：超时、连接断开
+func translateCommonError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrConnection
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ErrTimeout
+		}
+		return ErrConnection
+	}
+	return nil
+}
+
+// 将recover到的异常转换成错误
+func panicToError(exp any) error {
+	if err, isErr := exp.(error); isErr {
+		return err
+	}
+	return fmt.Errorf("%v", exp)
+}
@@ -0,0 +1,100 @@
+package data
+
+import (
+	"time"
+
+	"github.com/farseer-go/fs/configure"
+	"github.com/farseer-go/fs/flog"
+)
+
+// 连接池数量的默认上限
+const defaultMaxPools = 100
+
+// 动态连接池默认空闲多少秒后关闭
+const defaultPoolIdleSeconds = 300
+
+// 检查空闲动态连接池的间隔
+const poolEvictInterval = 30 * time.Second
+
+// 连接池数量的上限（./config.yaml的Data.MaxPools），默认100，小于0时不限制
+// 超过上限时淘汰最久未使用的动态连接池（通过NewInternalContext创建，如每个租户一个数据库），注册的上下文不会被淘汰
+func getMaxPools() int {
+	if maxPools := configure.GetInt("Data.MaxPools"); maxPools != 0 {
+		return maxPools
+	}
+	return defaultMaxPools
+}
+
+// 动态连接池空闲多久后关闭（./config.yaml的Data.PoolIdleSeconds），默认300秒，小于0时不关闭
+func getPoolIdleTimeout() time.Duration {
+	idleSeconds := configure.GetInt("Data.PoolIdleSeconds")
+	if idleSeconds == 0 {
+		idleSeconds = defaultPoolIdleSeconds
+	}
+	return time.Duration(idleSeconds) * time.Second
+}
+
+// 超过连接池数量上限时，从databaseConn中移除最久未使用的动态连接池（需要持有lock），由调用方关闭
+func evictOverLimit(keepKey string) []*databasePool {
+	limit := getMaxPools()
+	if limit < 0 {
+		return nil
+	}
+
+	var evicted []*databasePool
+	for len(databaseConn) > limit {
+		var lruKey string
+		for key, pool := range databaseConn {
+			if !pool.dbConfig.dynamic || key == keepKey {
+				continue
+			}
+			if lruKey == "" || pool.lastUsed.Before(databaseConn[lruKey].lastUsed) {
+				lruKey = key
+			}
+		}
+		// 只剩下注册的上下文，无法淘汰
+		if lruKey == "" {
+			flog.Warningf("连接池数量%d超过上限%d（Data.MaxPools），但没有可以淘汰的动态连接池", len(databaseConn), limit)
+			break
+		}
+		evicted = append(evicted, databaseConn[lruKey])
+		delete(databaseConn, lruKey)
+	}
+	return evicted
+}
+
+// 定时关闭空闲的动态连接池（超过PoolIdleSeconds未使用，且没有使用中的连接）
+func evictIdlePools(stop chan struct{}) {
+	ticker := time.NewTicker(poolEvictInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		idleTimeout := getPoolIdleTimeout()
+		if idleTimeout < 0 {
+			continue
+		}
+
+		var evicted []*databasePool
+		lock.Lock()
+		for key, pool := range databaseConn {
+			if !pool.dbConfig.dynamic || time.Since(pool.lastUsed) < idleTimeout {
+				continue
+			}
+			if sqlDB, err := pool.gormDB.DB(); err == nil && sqlDB.Stats().InUse > 0 {
+				continue
+			}
+			evicted = append(evicted, pool)
+			delete(databaseConn, key)
+		}
+		lock.Unlock()
+
+		for _, pool := range evicted {
+			drainPool(pool, pool.dbConfig.getDrainTimeout())
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/farseer-go/fs/flog"
+	"github.com/farseer-go/fs/trace"
+	"gorm.io/gorm"
+)
+
+// 默认检查当前主机、尝试切回主库的间隔（秒）
+const defaultFailoverCheckSeconds = 10
+
+// 检查主机是否可用的超时时间
+const hostProbeTimeout = 5 * time.Second
+
+// 故障转移的状态
+type failoverState struct {
+	active     int32 // 当前使用的主机（hosts的索引）
+	monitoring int32 // 是否已启动定时检查
+}
+
+// 第index个主机的配置，除连接字符串外与dbConfig相同
+func (receiver *dbConfig) hostConfig(index int) *dbConfig {
+	config := *receiver
+	config.ConnectionString = receiver.hosts[index]
+	config.redacted = redactConnectionString(config.DataType, config.ConnectionString)
+	config.hosts = nil
+	config.failover = nil
+	if receiver.secretOptions != nil {
+		hostOptions := receiver.secretOptions.host(index)
+		config.secretOptions = &hostOptions
+	}
+	return &config
+}
+
+// 是否要求可写的主库
+func (receiver *dbConfig) requireWritable() bool {
+	switch strings.ToLower(receiver.TargetSessionAttrs) {
+	case "read-write", "primary":
+		return true
+	}
+	return false
+}
+
+// 创建连接池：配置了多个主机时，按顺序连接第一个可用的主机（主库优先）
+func createPool(dbConfig *dbConfig) (*gorm.DB, error) {
+	if len(dbConfig.hosts) == 0 {
+		return connectHost(dbConfig)
+	}
+
+	var errs []string
+	for index := range dbConfig.hosts {
+		hostConfig := dbConfig.hostConfig(index)
+		gormDB, err := connectHost(hostConfig)
+		if err == nil {
+			if from := atomic.SwapInt32(&dbConfig.failover.active, int32(index)); int(from) != index {
+				traceFailover(dbConfig, int(from), index, "打开数据库时，排在前面的主机不可用")
+			}
+			return gormDB, nil
+		}
+		errs = append(errs, hostConfig.redacted+"："+err.Error())
+	}
+	return nil, &dbError{kind: ErrConnection, err: fmt.Errorf("[%s]所有主机都不可用：%s", dbConfig.displayName(), strings.Join(errs, "；"))}
+}
+
+// 连接主机，并检查是否满足TargetSessionAttrs
+func connectHost(dbConfig *dbConfig) (*gorm.DB, error) {
+	gormDB, err := connect(dbConfig)
+	if err != nil || !dbConfig.requireWritable() {
+		return gormDB, err
+	}
+	if err = probeHost(gormDB, dbConfig); err != nil {
+		closeDB(gormDB)
+		return nil, err
+	}
+	return gormDB, nil
+}
+
+// 检查主机是否可用，要求可写时检查是否为主库
+func probeHost(gormDB *gorm.DB, dbConfig *dbConfig) error {
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hostProbeTimeout)
+	defer cancel()
+	if err = sqlDB.PingContext(ctx); err != nil {
+		return err
+	}
+	if !dbConfig.requireWritable() {
+		return nil
+	}
+	checker, isChecker := dbConfig.GetDataDriver().(IWritableChecker)
+	if !isChecker {
+		return nil
+	}
+	writable, err := checker.IsWritable(ctx, sqlDB)
+	if err != nil {
+		return err
+	}
+	if !writable {
+		return fmt.Errorf("%s 是只读的从库", dbConfig.redacted)
+	}
+	return nil
+}
+
+// 定时检查当前主机：不可用时按顺序切换到其它主机，使用非主库时尝试切回主库
+// 连接池被替换（配置热更新）或关闭后退出
+func monitorFailover(dbConfig *dbConfig) {
+	if dbConfig.failover == nil || !atomic.CompareAndSwapInt32(&dbConfig.failover.monitoring, 0, 1) {
+		return
+	}
+	interval := time.Duration(dbConfig.FailoverCheckSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultFailoverCheckSeconds * time.Second
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&dbConfig.failover.monitoring, 0)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			lock.Lock()
+			pool, exists := databaseConn[dbConfig.keyName]
+			lock.Unlock()
+			if !exists || pool.dbConfig != dbConfig {
+				return
+			}
+			checkFailover(pool)
+		}
+	}()
+}
+
+// 检查当前主机，需要时切换主机
+func checkFailover(pool *databasePool) {
+	dbConfig := pool.dbConfig
+	active := int(atomic.LoadInt32(&dbConfig.failover.active))
+
+	// 主库恢复后切回
+	if active != 0 {
+		if gormDB, err := connectHost(dbConfig.hostConfig(0)); err == nil {
+			switchHost(pool, gormDB, 0, "主库已恢复")
+			return
+		}
+	}
+
+	err := probeHost(pool.gormDB, dbConfig.hostConfig(active))
+	if err == nil {
+		return
+	}
+	// 当前主机不可用，按顺序切换到其它可用的主机
+	for index := range dbConfig.hosts {
+		if index == active || (index == 0 && active != 0) {
+			continue
+		}
+		if gormDB, connectErr := connectHost(dbConfig.hostConfig(index)); connectErr == nil {
+			switchHost(pool, gormDB, index, "当前主机不可用："+err.Error())
+			return
+		}
+	}
+	flog.Warningf("[config.yaml]Database.%s 当前主机不可用，且没有其它可用的主机：%s", dbConfig.displayName(), err.Error())
+}
+
+// 切换到新的主机，旧的连接池等进行中的查询完成后关闭
+func switchHost(old *databasePool, gormDB *gorm.DB, index int, reason string) {
+	dbConfig := old.dbConfig
+	lock.Lock()
+	current, exists := databaseConn[dbConfig.keyName]
+	// 检查期间连接池已被替换或关闭
+	if !exists || current != old {
+		lock.Unlock()
+		if sqlDB, err := gormDB.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+		return
+	}
+	databaseConn[dbConfig.keyName] = &databasePool{gormDB: gormDB, dbConfig: dbConfig, lastUsed: old.lastUsed}
+	lock.Unlock()
+
+	from := atomic.SwapInt32(&dbConfig.failover.active, int32(index))
+	traceFailover(dbConfig, int(from), index, reason)
+	warmPool(gormDB, dbConfig)
+	go drainPool(old, dbConfig.getDrainTimeout())
+}
+
+// 记录故障转移的事件
+func traceFailover(dbConfig *dbConfig, from int, to int, reason string) {
+	fromHost := redactConnectionString(dbConfig.DataType, dbConfig.hosts[from])
+	toHost := redactConnectionString(dbConfig.DataType, dbConfig.hosts[to])
+	trace.Manager().TraceHand(fmt.Sprintf("数据库故障转移：%s，%s -> %s", dbConfig.displayName(), fromHost, toHost)).End(nil)
+	flog.Warningf("[config.yaml]Database.%s 故障转移：%s -> %s，原因：%s", dbConfig.displayName(), fromHost, toHost, reason)
+}
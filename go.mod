@@ -6,6 +6,7 @@ require (
 	github.com/farseer-go/collections v0.17.3
 	github.com/farseer-go/fs v0.17.3
 	github.com/farseer-go/mapper v0.17.3
+	github.com/go-sql-driver/mysql v1.9.3
 	github.com/govalues/decimal v0.1.36
 	gorm.io/driver/mysql v1.6.0
 	gorm.io/gorm v1.31.1
@@ -14,7 +15,6 @@ require (
 
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
-	github.com/go-sql-driver/mysql v1.9.3 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
@@ -27,5 +27,10 @@ func (c *healthCheck) Check() (string, error) {
 	}
 
 	tx := original.Raw(sql).Scan(&dbAt)
-	return fmt.Sprintf("Database.%s => %s", c.name, dbAt.Format("2006-01-02 15:04:05")), tx.Error
+	result := fmt.Sprintf("Database.%s => %s", c.name, dbAt.Format("2006-01-02 15:04:05"))
+	// 连接池的使用情况
+	if poolStats, err := c.PoolStats(); err == nil {
+		result += " " + poolStats.String()
+	}
+	return result, tx.Error
 }
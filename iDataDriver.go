@@ -1,9 +1,76 @@
 package data
 
-import "gorm.io/gorm"
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
 
 type IDataDriver interface {
 	GetDriver(connectionString string) gorm.Dialector
 	// CreateIndex 创建索引的SQL
 	CreateIndex(tableName string, idxName string, idxField IdxField) string
 }
+
+// IInsertIgnorer 新增记录时，忽略主键、唯一键冲突所需的子句（可选实现，未实现时使用gorm通用的ON CONFLICT DO NOTHING）
+type IInsertIgnorer interface {
+	// InsertIgnore 各数据库方言不同
+	InsertIgnore() []clause.Expression
+}
+
+// IErrorTranslator 转换驱动的原生错误（可选实现，未实现时只识别超时、连接断开等与驱动无关的错误）
+type IErrorTranslator interface {
+	// TranslateError 将驱动的原生错误码转换成统一的错误类型（ErrDuplicateKey、ErrDeadlock等），无法识别时返回nil
+	TranslateError(err error) error
+}
+
+// IConnectionStringBuilder 根据结构化的配置生成连接字符串（可选实现，未实现时只能配置ConnectionString）
+type IConnectionStringBuilder interface {
+	// CreateConnectionString 各数据库的DSN格式不同
+	CreateConnectionString(options DatabaseOptions) string
+}
+
+// IConnectionStringParser 解析连接字符串（可选实现，未实现时脱敏为******，诊断时跳过DNS、TCP检查）
+type IConnectionStringParser interface {
+	// ParseConnectionString 得到不包含密码的连接信息
+	ParseConnectionString(connectionString string) (ConnectionInfo, error)
+}
+
+// IBulkLoader 驱动原生的批量导入（可选实现，未实现时TableSet.BulkLoad退化为分批InsertList）
+type IBulkLoader interface {
+	// BulkLoad 使用数据库原生的导入方式写入tableName
+	// nextRow每次返回一行与columns顺序对应的值，读完时返回io.EOF
+	// 数据库拒绝原生导入（如未开启）且尚未读取任何行时，返回ErrBulkLoadNotSupported，退化为分批InsertList
+	BulkLoad(sqlDB *sql.DB, tableName string, columns []string, nextRow func() ([]any, error)) (int64, error)
+}
+
+// ILocker 驱动原生的分布式锁（可选实现，未实现时使用data_lock表的租约锁）
+// 锁的持有者为conn这个连接，连接关闭时数据库会自动释放锁
+type ILocker interface {
+	// Lock 获取锁，最多等待timeout，timeout为0时不等待，返回是否获取到
+	Lock(conn *sql.Conn, name string, timeout time.Duration) (bool, error)
+	// Unlock 释放锁
+	Unlock(conn *sql.Conn, name string) error
+}
+
+// IWritableChecker 判断连接的是否为可写的主库（可选实现，未实现时认为可写）
+// 用于TargetSessionAttrs=read-write：故障转移时跳过只读的从库
+type IWritableChecker interface {
+	// IsWritable 当前连接的数据库是否可写
+	IsWritable(ctx context.Context, sqlDB *sql.DB) (bool, error)
+}
+
+// ITLSDriver 驱动原生的TLS配置（可选实现，未实现时不支持TlsMode）
+// 统一的TLSOptions先转换成tls.Config，再由驱动设置到原生的配置中（如mysql的Config.TLS、clickhouse的Options.TLS）
+// 连接检查也使用原生的连接器，先在超时时间内Ping成功，再交给gorm
+type ITLSDriver interface {
+	// TLSConnector 使用tlsConfig创建连接器，tlsConfig为nil时使用连接字符串中的TLS配置
+	TLSConnector(connectionString string, tlsConfig *tls.Config) (driver.Connector, error)
+	// GetDriverWithConn 使用已打开的连接池得到Dialector
+	GetDriverWithConn(conn gorm.ConnPool) gorm.Dialector
+}
@@ -0,0 +1,480 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/farseer-go/fs/container"
+	"github.com/farseer-go/fs/core"
+	"github.com/farseer-go/fs/flog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// migrationHistoryTableName 系统表名称：记录每个上下文已执行的版本化迁移
+const migrationHistoryTableName = "data_migration_history"
+
+// 等待其它实例执行迁移的最长时间
+const migrateLockTimeout = 5 * time.Minute
+
+// MigrationFunc Go代码实现的迁移，db为迁移使用的连接（支持事务的数据库中，与历史记录在同一个事务中）
+type MigrationFunc func(db *gorm.DB) error
+
+// Migration 版本化的迁移，按Version从小到大执行
+// 用于AutoMigrate无法表达的变更：重命名字段、修改类型、回填数据等
+type Migration struct {
+	Version int64         // 版本号，同一个上下文中不能重复
+	Name    string        // 名称
+	Up      MigrationFunc // 升级
+	Down    MigrationFunc // 回滚，为nil时不支持回滚
+
+	upScripts   map[string]string // SQL文件的升级脚本：方言 -> 脚本，""为通用的脚本
+	downScripts map[string]string // SQL文件的回滚脚本
+}
+
+// migrationHistoryPO 系统表 data_migration_history 的映射
+type migrationHistoryPO struct {
+	KeyName    string    `gorm:"column:key_name;type:varchar(64);primaryKey"`   // 上下文配置名（config.yaml中的Database节点名）
+	Version    int64     `gorm:"column:version;primaryKey;autoIncrement:false"` // 版本号
+	Name       string    `gorm:"column:name;type:varchar(128)"`                 // 名称
+	Checksum   string    `gorm:"column:checksum;type:varchar(64)"`              // SQL脚本的校验和（Go代码实现的迁移为空）
+	DataType   string    `gorm:"column:data_type;type:varchar(32)"`             // 驱动类型（mysql/clickhouse等）
+	Elapsed    int64     `gorm:"column:elapsed"`                                // 执行耗时（毫秒）
+	RolledBack bool      `gorm:"column:rolled_back"`                            // 是否已回滚
+	AppliedAt  time.Time `gorm:"column:applied_at"`                             // 执行时间（ClickHouse下同时作为ReplacingMergeTree的版本列）
+}
+
+// TableName 指定系统表名
+func (migrationHistoryPO) TableName() string {
+	return migrationHistoryTableName
+}
+
+var (
+	migrations    = make(map[string]map[int64]*Migration) // keyName -> (版本号 -> 迁移)
+	migrationLock sync.Mutex
+)
+
+// 迁移文件名：0001_create_user.up.sql、0001_create_user.down.sql、0002_rename.up.postgresql.sql（指定方言）
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)_(.+?)\.(up|down)(?:\.([a-z0-9]+))?\.sql$`)
+
+// RegisterMigration 注册Go代码实现的迁移，keyName为数据库配置名称（./config.yaml的Database节点名）
+// 在PreInitialize中注册时，由data.Module.Initialize执行；之后注册的，需要调用Migrate执行
+func RegisterMigration(keyName string, migration Migration) error {
+	if migration.Up == nil {
+		return fmt.Errorf("迁移%d_%s的Up不能为nil", migration.Version, migration.Name)
+	}
+	return addMigration(keyName, &migration)
+}
+
+// RegisterMigrationFS 注册目录中的SQL迁移文件（通常为embed.FS），keyName为数据库配置名称
+// 文件名格式：NNNN_名称.up.sql、NNNN_名称.down.sql，方言的脚本优先于通用的脚本：NNNN_名称.up.mysql.sql
+func RegisterMigrationFS(keyName string, fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("读取迁移目录%s失败：%w", dir, err)
+	}
+
+	loaded := make(map[int64]*Migration)
+	for _, entry := range entries {
+		matches := migrationFileRegexp.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+		version, _ := strconv.ParseInt(matches[1], 10, 64)
+		migration, exists := loaded[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: matches[2], upScripts: make(map[string]string), downScripts: make(map[string]string)}
+			loaded[version] = migration
+		} else if migration.Name != matches[2] {
+			return fmt.Errorf("迁移版本号%d重复：%s、%s", version, migration.Name, matches[2])
+		}
+
+		script, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("读取迁移文件%s失败：%w", entry.Name(), err)
+		}
+		scripts := migration.upScripts
+		if matches[3] == "down" {
+			scripts = migration.downScripts
+		}
+		scripts[normalizeDialect(matches[4])] = strings.ReplaceAll(string(script), "\r\n", "\n")
+	}
+
+	for _, migration := range loaded {
+		if len(migration.upScripts) == 0 {
+			return fmt.Errorf("迁移%d_%s缺少up脚本", migration.Version, migration.Name)
+		}
+		if err = addMigration(keyName, migration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addMigration(keyName string, migration *Migration) error {
+	migrationLock.Lock()
+	defer migrationLock.Unlock()
+
+	if _, exists := migrations[keyName]; !exists {
+		migrations[keyName] = make(map[int64]*Migration)
+	}
+	if registered, exists := migrations[keyName][migration.Version]; exists {
+		return fmt.Errorf("迁移版本号%d重复：%s、%s", migration.Version, registered.Name, migration.Name)
+	}
+	migrations[keyName][migration.Version] = migration
+	return nil
+}
+
+// 按版本号排序的迁移
+func getMigrations(keyName string) []*Migration {
+	migrationLock.Lock()
+	defer migrationLock.Unlock()
+
+	lst := make([]*Migration, 0, len(migrations[keyName]))
+	for _, migration := range migrations[keyName] {
+		lst = append(lst, migration)
+	}
+	sort.Slice(lst, func(i, j int) bool { return lst[i].Version < lst[j].Version })
+	return lst
+}
+
+// 方言的别名
+func normalizeDialect(dataType string) string {
+	switch dataType = strings.ToLower(dataType); dataType {
+	case "postgres":
+		return "postgresql"
+	case "mssql":
+		return "sqlserver"
+	}
+	return dataType
+}
+
+// 得到当前数据库的迁移方法及SQL脚本的校验和
+func (receiver *Migration) resolve(dataType string, up bool) (MigrationFunc, string, error) {
+	fn, scripts, direction := receiver.Up, receiver.upScripts, "up"
+	if !up {
+		fn, scripts, direction = receiver.Down, receiver.downScripts, "down"
+	}
+	// Go代码实现的迁移
+	if receiver.Up != nil {
+		if fn == nil {
+			return nil, "", fmt.Errorf("迁移%d_%s不支持回滚", receiver.Version, receiver.Name)
+		}
+		return fn, "", nil
+	}
+
+	script, exists := scripts[normalizeDialect(dataType)]
+	if !exists {
+		script, exists = scripts[""]
+	}
+	if !exists {
+		return nil, "", fmt.Errorf("迁移%d_%s没有%s的%s脚本", receiver.Version, receiver.Name, dataType, direction)
+	}
+	checksum := sha256.Sum256([]byte(script))
+	return func(db *gorm.DB) error {
+		for _, statement := range splitStatements(script) {
+			if err := db.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}, hex.EncodeToString(checksum[:]), nil
+}
+
+// Migrate 执行所有未执行的迁移
+func (receiver *internalContext) Migrate() error {
+	return receiver.migrate(func(lst []*Migration, applied map[int64]migrationHistoryPO) ([]*Migration, bool) {
+		return lst, true
+	})
+}
+
+// MigrateTo 迁移到指定的版本：高于当前版本时升级，低于当前版本时回滚已执行的、大于version的迁移
+func (receiver *internalContext) MigrateTo(version int64) error {
+	return receiver.migrate(func(lst []*Migration, applied map[int64]migrationHistoryPO) ([]*Migration, bool) {
+		var ups, downs []*Migration
+		for _, migration := range lst {
+			if migration.Version <= version {
+				ups = append(ups, migration)
+			} else if _, exists := applied[migration.Version]; exists {
+				downs = append([]*Migration{migration}, downs...)
+			}
+		}
+		if len(downs) > 0 {
+			return downs, false
+		}
+		return ups, true
+	})
+}
+
+// RollbackMigration 回滚最近执行的steps个迁移
+func (receiver *internalContext) RollbackMigration(steps int) error {
+	return receiver.migrate(func(lst []*Migration, applied map[int64]migrationHistoryPO) ([]*Migration, bool) {
+		var downs []*Migration
+		for index := len(lst) - 1; index >= 0 && len(downs) < steps; index-- {
+			if _, exists := applied[lst[index].Version]; exists {
+				downs = append(downs, lst[index])
+			}
+		}
+		return downs, false
+	})
+}
+
+// 加锁后（多个实例同时启动时只有一个执行），由plan得到需要升级或回滚的迁移，按顺序执行
+func (receiver *internalContext) migrate(plan func(lst []*Migration, applied map[int64]migrationHistoryPO) ([]*Migration, bool)) error {
+	lst := getMigrations(receiver.dbConfig.keyName)
+	if len(lst) == 0 {
+		return nil
+	}
+
+	lock, err := receiver.Lock("data_migrate:"+receiver.dbConfig.keyName, migrateLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	// 使用注册的数据库，不按租户路由
+	gormDB, err := openPool(receiver.dbConfig)
+	if err != nil {
+		return err
+	}
+	db := gormDB.Session(&gorm.Session{})
+	applied, err := receiver.loadMigrationHistory(db)
+	if err != nil {
+		return err
+	}
+
+	// 已执行的SQL脚本被修改时，不再继续执行
+	for _, migration := range lst {
+		history, exists := applied[migration.Version]
+		if !exists || history.Checksum == "" {
+			continue
+		}
+		if _, checksum, err := migration.resolve(receiver.dbConfig.DataType, true); err == nil && checksum != history.Checksum {
+			return fmt.Errorf("迁移%d_%s已执行，但脚本被修改（校验和不一致）", migration.Version, migration.Name)
+		}
+	}
+
+	migrates, up := plan(lst, applied)
+	for _, migration := range migrates {
+		if _, exists := applied[migration.Version]; exists == up {
+			continue
+		}
+		if err = receiver.runMigration(db, migration, up); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 执行一个迁移，并记录到系统表（支持事务的数据库中，在同一个事务中执行）
+func (receiver *internalContext) runMigration(db *gorm.DB, migration *Migration, up bool) error {
+	fn, checksum, err := migration.resolve(receiver.dbConfig.DataType, up)
+	if err != nil {
+		return err
+	}
+
+	startAt := time.Now()
+	execute := func(tx *gorm.DB) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return receiver.recordMigration(tx, migrationHistoryPO{
+			KeyName:    receiver.dbConfig.keyName,
+			Version:    migration.Version,
+			Name:       migration.Name,
+			Checksum:   checksum,
+			DataType:   receiver.dbConfig.DataType,
+			Elapsed:    time.Since(startAt).Milliseconds(),
+			RolledBack: !up,
+			AppliedAt:  time.Now(),
+		})
+	}
+	// ClickHouse不支持事务
+	if receiver.dbConfig.DataType == "clickhouse" {
+		err = execute(db)
+	} else {
+		err = db.Transaction(execute)
+	}
+
+	direction := "升级"
+	if !up {
+		direction = "回滚"
+	}
+	if err != nil {
+		return fmt.Errorf("[config.yaml]Database.%s %s迁移%d_%s失败：%w", receiver.dbConfig.displayName(), direction, migration.Version, migration.Name, receiver.dbConfig.translateError(err))
+	}
+	flog.Infof("[config.yaml]Database.%s %s迁移%d_%s，耗时：%s", receiver.dbConfig.displayName(), direction, migration.Version, migration.Name, time.Since(startAt))
+	return nil
+}
+
+// 确保系统表存在，并读取已执行（未回滚）的迁移
+func (receiver *internalContext) loadMigrationHistory(db *gorm.DB) (map[int64]migrationHistoryPO, error) {
+	if !db.Migrator().HasTable(migrationHistoryTableName) {
+		var err error
+		if receiver.dbConfig.DataType == "clickhouse" {
+			// ClickHouse无真正的UPDATE，用ReplacingMergeTree按(key_name,version)去重，applied_at作为版本列（取最新）
+			ddl := "CREATE TABLE IF NOT EXISTS " + migrationHistoryTableName + " (" +
+				"key_name String, version Int64, name String, checksum String, data_type String, " +
+				"elapsed Int64, rolled_back Bool, applied_at DateTime64(3)" +
+				") ENGINE = ReplacingMergeTree(applied_at) ORDER BY (key_name, version)"
+			err = db.Exec(ddl).Error
+		} else {
+			err = db.AutoMigrate(&migrationHistoryPO{})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("创建系统表%s失败：%w", migrationHistoryTableName, err)
+		}
+	}
+
+	var rows []migrationHistoryPO
+	query := db.Table(migrationHistoryTableName)
+	if receiver.dbConfig.DataType == "clickhouse" {
+		// ClickHouse需FINAL去重，确保读到最新的记录
+		query = query.Clauses(FinalHint{})
+	}
+	if err := query.Where("key_name = ?", receiver.dbConfig.keyName).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("读取系统表%s失败：%w", migrationHistoryTableName, err)
+	}
+
+	applied := make(map[int64]migrationHistoryPO)
+	for _, row := range rows {
+		if !row.RolledBack {
+			applied[row.Version] = row
+		}
+	}
+	return applied, nil
+}
+
+// 记录迁移的执行（回滚时标记为已回滚，保留历史）
+func (receiver *internalContext) recordMigration(db *gorm.DB, po migrationHistoryPO) error {
+	if receiver.dbConfig.DataType == "clickhouse" {
+		// ClickHouse：直接追加一行，由ReplacingMergeTree+FINAL保证下次读到最新（同schemaMigrate，Transaction必须这么使用，否则数据库查不到数据）
+		return db.Table(migrationHistoryTableName).Transaction(func(tx *gorm.DB) error {
+			return tx.Create(&po).Error
+		})
+	}
+	// 其余驱动：按主键(key_name,version)做upsert
+	return db.Table(migrationHistoryTableName).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key_name"}, {Name: "version"}},
+		UpdateAll: true,
+	}).Create(&po).Error
+}
+
+// 执行PreInitialize中注册的迁移，失败时终止启动
+func runRegisteredMigrations() {
+	migrationLock.Lock()
+	keys := make([]string, 0, len(migrations))
+	for key := range migrations {
+		keys = append(keys, key)
+	}
+	migrationLock.Unlock()
+
+	for _, key := range keys {
+		if !container.IsRegister[core.ITransaction](key) {
+			continue
+		}
+		ins, isInternalContext := container.Resolve[core.ITransaction](key).(*internalContext)
+		if !isInternalContext {
+			continue
+		}
+		if err := ins.Migrate(); err != nil {
+			panic(err.Error())
+		}
+	}
+}
+
+// 将SQL脚本拆分成单条语句（按分号拆分，忽略引号、注释、postgres的$$中的分号）
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+
+	for index := 0; index < len(script); index++ {
+		char := script[index]
+		switch {
+		case char == '\'' || char == '"' || char == '`':
+			// 字符串、标识符，两个连续的引号为转义
+			end := index + 1
+			for ; end < len(script); end++ {
+				if script[end] == char {
+					if end+1 < len(script) && script[end+1] == char {
+						end++
+						continue
+					}
+					break
+				}
+			}
+			if end >= len(script) {
+				end = len(script) - 1
+			}
+			current.WriteString(script[index : end+1])
+			index = end
+		case char == '-' && strings.HasPrefix(script[index:], "--"):
+			// 单行注释
+			end := strings.IndexByte(script[index:], '\n')
+			if end < 0 {
+				end = len(script) - index
+			}
+			index += end
+			current.WriteByte('\n')
+		case char == '/' && strings.HasPrefix(script[index:], "/*"):
+			// 多行注释
+			end := strings.Index(script[index+2:], "*/")
+			if end < 0 {
+				index = len(script)
+			} else {
+				index += end + 3
+			}
+			current.WriteByte(' ')
+		case char == '$':
+			// postgres的$tag$...$tag$
+			tag := dollarQuoteTag(script[index:])
+			if tag == "" {
+				current.WriteByte(char)
+				continue
+			}
+			end := strings.Index(script[index+len(tag):], tag)
+			if end < 0 {
+				end = len(script) - index - len(tag)
+			} else {
+				end += len(tag)
+			}
+			current.WriteString(script[index : index+len(tag)+end])
+			index += len(tag) + end - 1
+		case char == ';':
+			flush()
+		default:
+			current.WriteByte(char)
+		}
+	}
+	flush()
+	return statements
+}
+
+// postgres的$tag$，不是时返回空
+func dollarQuoteTag(script string) string {
+	for index := 1; index < len(script); index++ {
+		char := script[index]
+		if char == '$' {
+			return script[:index+1]
+		}
+		if !(char == '_' || char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || index > 1 && char >= '0' && char <= '9') {
+			return ""
+		}
+	}
+	return ""
+}
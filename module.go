@@ -5,7 +5,6 @@ import (
 	"github.com/farseer-go/fs/container"
 	"github.com/farseer-go/fs/core"
 	"github.com/farseer-go/fs/modules"
-	"gorm.io/gorm"
 )
 
 type Module struct {
@@ -16,22 +15,29 @@ func (module Module) DependsModule() []modules.FarseerModule {
 }
 
 func (module Module) PreInitialize() {
-	databaseConn = make(map[string]*gorm.DB)
+	databaseConn = make(map[string]*databasePool)
 	// 注册包级别的连接检查器（默认实现）
 	container.Register(func() core.IConnectionChecker { return &connectionChecker{} }, "data")
+	// 注册mysql驱动（驱动需要在Initialize解析Database配置之前注册，结构化的配置由驱动生成连接字符串）
+	container.Register(func() IDataDriver { return &DataDriver{} }, "mysql")
+	// 注册内置的密钥提供者：${ENV:NAME}、${FILE:/run/secrets/db}
+	container.Register(func() ISecretProvider { return &envSecretProvider{} }, "env")
+	container.Register(func() ISecretProvider { return &fileSecretProvider{} }, "file")
 }
 
 func (module Module) Initialize() {
-	// 注册mysql驱动
-	container.Register(func() IDataDriver { return &DataDriver{} }, "mysql")
-
 	nodes := configure.GetSubNodes("Database")
 	for key, val := range nodes {
-		configString := val.(string)
-		if configString == "" {
-			panic("[config.yaml]Database." + key + "，配置不正确")
-		}
-		// 注册内部上下文
-		RegisterInternalContext(key, configString)
+		registerConfigNode(key, val)
 	}
+	// 执行PreInitialize中注册的版本化迁移
+	runRegisteredMigrations()
+	// 开启了Data.ConfigWatchSeconds时，配置文件修改后重新加载Database节点
+	startConfigWatcher()
+}
+
+// Shutdown 应用关闭时，等待进行中的查询完成后关闭所有连接池（每个连接池最多等待DrainSeconds）
+func (module Module) Shutdown() {
+	stopConfigWatcher()
+	closeAllPools()
 }
@@ -11,74 +11,137 @@ import (
 	"gorm.io/gorm"
 )
 
-var databaseConn map[string]*gorm.DB
+var databaseConn map[string]*databasePool
 var lock sync.Mutex
 
-// 打开数据库（全局）
+// 正在创建的连接池，同一个keyName只创建一次，其余的调用方等待创建完成
+var pendingPools = make(map[string]*pendingPool)
+
+// 已打开的连接池
+type databasePool struct {
+	gormDB   *gorm.DB
+	dbConfig *dbConfig
+	lastUsed time.Time // 最近一次使用的时间，用于淘汰动态连接池
+}
+
+// 正在创建的连接池
+type pendingPool struct {
+	done   chan struct{} // 创建完成后关闭
+	gormDB *gorm.DB
+	err    error
+}
+
+// 打开数据库（业务数据），注册了租户解析器时，TenantDatabase路由到租户的数据库
+// 锁、发件箱、连接池统计等基础设施使用注册的数据库（openPool），不按租户路由
 func open(dbConfig *dbConfig) (*gorm.DB, error) {
-	db, exists := databaseConn[dbConfig.keyName]
-	// 不存在，则创建
-	if !exists {
-		lock.Lock()
-		defer lock.Unlock()
-		traceManager := trace.Manager()
-		traceDatabase := traceManager.TraceDatabaseOpen(dbConfig.databaseName, dbConfig.ConnectionString)
-		// 连接数据库参考：https://gorm.io/zh_CN/docs/connecting_to_the_database.html
-		// Data Source ClientName 参考 https://github.com/go-sql-driver/mysql#dsn-data-source-name
+	current, err := dbConfig.getTenant()
+	if err != nil {
+		return nil, err
+	}
+	return openPool(current.getDbConfig(dbConfig))
+}
 
-		gormDB, err := gorm.Open(dbConfig.GetDriver(), &gorm.Config{
-			SkipDefaultTransaction:                   true,
-			DisableForeignKeyConstraintWhenMigrating: true, // 禁止自动创建数据库外键约束
-			Logger:                                   loggers.NewFsLogger(),
-			// Logger: logger.New(
-			// 	log.New(os.Stdout, "\r\n", log.LstdFlags), // io writer
-			// 	logger.Config{
-			// 		SlowThreshold:             time.Second, // 慢 SQL 阈值
-			// 		Colorful:                  false,       // 禁用彩色打印
-			// 		IgnoreRecordNotFoundError: true,
-			// 		ParameterizedQueries:      false,
-			// 		LogLevel:                  logger.Info, // Log level
-			// 	},
-			// ),
-		})
-		defer traceDatabase.End(err)
-		if err != nil {
-			return gormDB, fmt.Errorf("打开[%s]数据库[%s]失败：%s", strings.ToLower(dbConfig.DataType), dbConfig.keyName, err.Error())
-		}
+// 打开连接池，同一个keyName共享连接池
+func openPool(dbConfig *dbConfig) (*gorm.DB, error) {
+	// 如果是动态连接，dbConfig.keyName是空的，不缓存
+	if dbConfig.keyName == "" {
+		return createPool(dbConfig)
+	}
 
-		_ = gormDB.Use(&TracePlugin{traceManager: traceManager})
-		// 设置池大小
-		setPool(gormDB, dbConfig)
-		// 如果是动态连接，dbConfig.keyName是空的
-		if dbConfig.keyName != "" {
-			databaseConn[dbConfig.keyName] = gormDB
-		}
-		db = gormDB
+	lock.Lock()
+	if pool, exists := databaseConn[dbConfig.keyName]; exists {
+		pool.lastUsed = time.Now()
+		lock.Unlock()
+		return pool.gormDB, nil
+	}
+	// 其它协程正在创建，等待创建完成
+	if pending, exists := pendingPools[dbConfig.keyName]; exists {
+		lock.Unlock()
+		<-pending.done
+		return pending.gormDB, pending.err
 	}
-	return db, nil
+	pending := &pendingPool{done: make(chan struct{})}
+	pendingPools[dbConfig.keyName] = pending
+	lock.Unlock()
+
+	// 创建连接池时不持有锁，不影响其它keyName的使用
+	pending.gormDB, pending.err = createPool(dbConfig)
+	var evicted []*databasePool
+	lock.Lock()
+	delete(pendingPools, dbConfig.keyName)
+	if pending.err == nil {
+		databaseConn[dbConfig.keyName] = &databasePool{gormDB: pending.gormDB, dbConfig: dbConfig, lastUsed: time.Now()}
+		// 超过连接池数量上限时，淘汰最久未使用的动态连接池
+		evicted = evictOverLimit(dbConfig.keyName)
+	}
+	lock.Unlock()
+	close(pending.done)
+	if pending.err != nil {
+		return pending.gormDB, pending.err
+	}
+
+	for _, pool := range evicted {
+		go drainPool(pool, pool.dbConfig.getDrainTimeout())
+	}
+	// 预先创建最小连接数
+	warmPool(pending.gormDB, dbConfig)
+	// 多个主机时，定时检查当前主机，不可用时切换到其它主机，主库恢复后切回
+	monitorFailover(dbConfig)
+	// 定时检查连接池是否饱和、淘汰空闲的动态连接池
+	startPoolMonitor()
+	return pending.gormDB, nil
 }
 
-// 设置池大小
-func setPool(gormDB *gorm.DB, dbConfig *dbConfig) {
-	sqlDB, _ := gormDB.DB()
+// 连接数据库，创建连接池
+func connect(dbConfig *dbConfig) (*gorm.DB, error) {
+	traceManager := trace.Manager()
+	traceDatabase := traceManager.TraceDatabaseOpen(dbConfig.databaseName, dbConfig.redacted)
+	// 连接数据库参考：https://gorm.io/zh_CN/docs/connecting_to_the_database.html
+	// Data Source ClientName 参考 https://github.com/go-sql-driver/mysql#dsn-data-source-name
 
-	// 建议：MaxOpenConns 和 MaxIdleConns 设置为相同值
-	// 这样可以避免连接频繁创建和销毁带来的性能损耗
-	// 建议值：根据你的并发量，设置为 10-20 比较合适
-	maxSize := dbConfig.PoolMaxSize
-	if maxSize < 10 {
-		maxSize = 10 // 强制最小10个，避免瓶颈
+	dialector, err := dbConfig.GetDriver()
+	if err != nil {
+		traceDatabase.End(err)
+		return nil, fmt.Errorf("打开[%s]数据库[%s]失败：%w", strings.ToLower(dbConfig.DataType), dbConfig.displayName(), err)
+	}
+	gormDB, err := gorm.Open(dialector, &gorm.Config{
+		SkipDefaultTransaction:                   true,
+		DisableForeignKeyConstraintWhenMigrating: true, // 禁止自动创建数据库外键约束
+		Logger:                                   loggers.NewFsLogger(),
+		// Logger: logger.New(
+		// 	log.New(os.Stdout, "\r\n", log.LstdFlags), // io writer
+		// 	logger.Config{
+		// 		SlowThreshold:             time.Second, // 慢 SQL 阈值
+		// 		Colorful:                  false,       // 禁用彩色打印
+		// 		IgnoreRecordNotFoundError: true,
+		// 		ParameterizedQueries:      false,
+		// 		LogLevel:                  logger.Info, // Log level
+		// 	},
+		// ),
+	})
+	defer traceDatabase.End(err)
+	if err != nil {
+		// gorm.Open失败（如Ping失败）时仍会创建sql.DB，需要关闭，否则每次重试都会泄漏一个连接池
+		closeDB(gormDB)
+		return nil, &dbError{kind: ErrConnection, err: fmt.Errorf("打开[%s]数据库[%s]失败：%w", strings.ToLower(dbConfig.DataType), dbConfig.displayName(), err)}
 	}
 
-	sqlDB.SetMaxOpenConns(maxSize) // 最大连接数
-	sqlDB.SetMaxIdleConns(maxSize) // 空闲连接数等于最大连接数（保持长连接）
+	// 使用了密钥占位符时，每次新建连接都重新解析密钥
+	useSecretConnector(gormDB, dbConfig)
+	_ = gormDB.Use(&TracePlugin{traceManager: traceManager})
+	_ = gormDB.Use(&ErrorPlugin{dataDriver: dbConfig.GetDataDriver()})
+	_ = gormDB.Use(&TenantPlugin{})
+	// 设置池大小
+	setPool(gormDB, dbConfig)
+	return gormDB, nil
+}
 
-	if strings.ToLower(dbConfig.DataType) == "clickhouse" {
-		// 这样可以避免每隔几分钟就重建连接
-		sqlDB.SetConnMaxLifetime(10 * time.Minute)
-		// 空闲超时：既然 MaxIdleConns 已经等于 MaxOpenConns，这个参数主要防止极端空闲
-		sqlDB.SetConnMaxIdleTime(5 * time.Minute)
-	} else {
-		sqlDB.SetConnMaxLifetime(time.Hour)
+// 关闭gormDB的连接池（gormDB可以为nil）
+func closeDB(gormDB *gorm.DB) {
+	if gormDB == nil {
+		return
+	}
+	if sqlDB, err := gormDB.DB(); err == nil {
+		_ = sqlDB.Close()
 	}
 }
@@ -0,0 +1,379 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/farseer-go/fs/container"
+	"github.com/farseer-go/fs/core"
+	"github.com/farseer-go/fs/exception"
+	"github.com/farseer-go/fs/flog"
+	"github.com/farseer-go/fs/snc"
+	"github.com/farseer-go/fs/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// outboxTableName 系统表名称：事务性发件箱，与业务数据在同一个事务中写入，由后台投递
+const outboxTableName = "data_outbox"
+
+// outbox消息的状态
+const (
+	outboxStatusPending = 0 // 待投递
+	outboxStatusSent    = 1 // 已投递
+	outboxStatusFailed  = 2 // 超过最大重试次数，不再投递
+)
+
+// outboxLeaseDuration 认领消息后的租约时间，超过后未完成投递的消息可被其它实例重新认领
+const outboxLeaseDuration = 5 * time.Minute
+
+// outboxPO 系统表 data_outbox 的映射
+type outboxPO struct {
+	Id         int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	KeyName    string    `gorm:"column:key_name;type:varchar(64);index:idx_outbox_pending,priority:1"` // 上下文配置名（config.yaml中的Database节点名），区分同库多上下文
+	Status     int       `gorm:"column:status;index:idx_outbox_pending,priority:2"`                    // 状态：0待投递、1已投递、2失败
+	NextAt     time.Time `gorm:"column:next_at;index:idx_outbox_pending,priority:3"`                   // 下次投递时间
+	Topic      string    `gorm:"column:topic;type:varchar(128)"`                                       // 消息主题
+	Payload    string    `gorm:"column:payload;type:text"`                                             // 消息内容（非字符串时序列化成JSON）
+	Attempts   int       `gorm:"column:attempts"`                                                      // 已投递的次数
+	LastError  string    `gorm:"column:last_error;type:varchar(512)"`                                  // 最后一次投递失败的原因
+	CreateAt   time.Time `gorm:"column:create_at"`                                                     // 写入时间
+	SentAt     time.Time `gorm:"column:sent_at"`                                                       // 投递成功的时间
+	LeaseUntil time.Time `gorm:"column:lease_until"`                                                   // 认领租约的到期时间（不支持跳过锁定行的数据库，用于多实例投递）
+}
+
+// TableName 指定系统表名
+func (outboxPO) TableName() string {
+	return outboxTableName
+}
+
+// OutboxMessage 待投递的消息
+type OutboxMessage struct {
+	Id       int64     // 消息ID，可用于消费端幂等
+	Topic    string    // 消息主题
+	Payload  string    // 消息内容
+	Attempts int       // 之前已投递的次数
+	CreateAt time.Time // 写入时间
+}
+
+// IOutboxPublisher 消息发布者（如MQ），由业务方注册到容器
+// 注册时的名称为数据库配置名称，未找到时使用默认（不带名称）的实现
+// Publish在锁定消息行的事务中（或认领租约期间）调用，应尽快返回；事务提交失败时已发布的消息会再次投递，消费方需要幂等
+type IOutboxPublisher interface {
+	// Publish 发布消息，返回错误时按退避策略稍后重试
+	Publish(message OutboxMessage) error
+}
+
+// OutboxOptions 后台投递的配置
+type OutboxOptions struct {
+	PollInterval time.Duration // 轮询间隔，默认1秒
+	BatchSize    int           // 每次最多投递的数量，默认100
+	MaxAttempts  int           // 最多投递的次数，超过后标记为失败，默认10
+	Backoff      time.Duration // 首次重试前的等待时间，之后每次翻倍（最长1小时），默认1秒
+}
+
+// 每个上下文是否已创建系统表
+var (
+	outboxLoaded = make(map[string]bool)
+	outboxLock   sync.Mutex
+)
+
+// ensureOutboxTable 确保系统表存在，每个keyName只真正执行一次
+func (receiver *internalContext) ensureOutboxTable() error {
+	outboxLock.Lock()
+	defer outboxLock.Unlock()
+
+	if outboxLoaded[receiver.dbConfig.keyName] {
+		return nil
+	}
+	if !receiver.dbConfig.supportTransaction() {
+		return fmt.Errorf("%s不支持事务，无法使用系统表%s", receiver.dbConfig.DataType, outboxTableName)
+	}
+
+	db, err := openPool(receiver.dbConfig)
+	if err != nil {
+		return err
+	}
+	db = db.Session(&gorm.Session{})
+	if !db.Migrator().HasTable(outboxTableName) {
+		if err = db.AutoMigrate(&outboxPO{}); err != nil {
+			return fmt.Errorf("创建系统表%s失败：%w", outboxTableName, err)
+		}
+	}
+	outboxLoaded[receiver.dbConfig.keyName] = true
+	return nil
+}
+
+// Enqueue 写入发件箱，在事务中时与业务数据一起提交，提交后由后台投递
+// payload为string、[]byte时原样保存，其它类型序列化成JSON
+// 发件箱保存在注册的数据库中（不按租户路由），因此不能加入TenantDatabase租户数据库的事务
+func (receiver *internalContext) Enqueue(topic string, payload any) error {
+	if err := receiver.ensureOutboxTable(); err != nil {
+		return err
+	}
+
+	db := getRoutineOrmClient(receiver.dbConfig.keyName).Get()
+	if db != nil {
+		current, err := receiver.dbConfig.getTenant()
+		if err != nil {
+			return err
+		}
+		if current.getDbConfig(receiver.dbConfig) != receiver.dbConfig {
+			return errOutboxTenantDatabase
+		}
+	} else {
+		var err error
+		if db, err = openPool(receiver.dbConfig); err != nil {
+			return err
+		}
+		db = db.Session(&gorm.Session{})
+	}
+	return enqueueOutbox(db, receiver.dbConfig.keyName, topic, payload)
+}
+
+// Enqueue 写入发件箱，与事务中的业务数据一起提交
+func (receiver *Tx) Enqueue(topic string, payload any) error {
+	if receiver.tenantDatabase {
+		return errOutboxTenantDatabase
+	}
+	dbContext := &internalContext{dbConfig: receiver.dbConfig}
+	if err := dbContext.ensureOutboxTable(); err != nil {
+		return err
+	}
+	return enqueueOutbox(receiver.Original(), receiver.dbConfig.keyName, topic, payload)
+}
+
+// 事务使用租户的数据库时，发件箱（注册的数据库）无法与业务数据一起提交
+var errOutboxTenantDatabase = errors.New("发件箱保存在注册的数据库中，不能在TenantDatabase租户数据库的事务中写入")
+
+// OutboxFallback 用于UnitOfWork.Fallback：不支持事务的数据库执行失败时，将消息写入dbName的发件箱，稍后重新投递
+func OutboxFallback(dbName string, topic string, payload any) func(err error) error {
+	return func(err error) error {
+		if !container.IsRegister[core.ITransaction](dbName) {
+			return fmt.Errorf("写入发件箱失败：[config.yaml]Database.%s 未注册，原始错误：%w", dbName, err)
+		}
+		return container.Resolve[core.ITransaction](dbName).(*internalContext).Enqueue(topic, payload)
+	}
+}
+
+// 写入发件箱
+func enqueueOutbox(db *gorm.DB, keyName string, topic string, payload any) error {
+	var content string
+	switch val := payload.(type) {
+	case string:
+		content = val
+	case []byte:
+		content = string(val)
+	default:
+		marshal, err := snc.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("写入发件箱失败，序列化消息出错：%w", err)
+		}
+		content = string(marshal)
+	}
+
+	now := time.Now()
+	return db.Table(outboxTableName).Create(&outboxPO{
+		KeyName:  keyName,
+		Status:   outboxStatusPending,
+		NextAt:   now,
+		Topic:    topic,
+		Payload:  content,
+		CreateAt: now,
+	}).Error
+}
+
+// StartOutboxDispatcher 启动后台投递，ctx取消时停止
+// 发布者从容器中获取：优先使用以数据库配置名称注册的IOutboxPublisher，否则使用默认的实现
+func (receiver *internalContext) StartOutboxDispatcher(ctx context.Context, options OutboxOptions) error {
+	var publisher IOutboxPublisher
+	switch {
+	case container.IsRegister[IOutboxPublisher](receiver.dbConfig.keyName):
+		publisher = container.Resolve[IOutboxPublisher](receiver.dbConfig.keyName)
+	case container.IsRegister[IOutboxPublisher]():
+		publisher = container.Resolve[IOutboxPublisher]()
+	default:
+		return fmt.Errorf("启动发件箱投递失败，未注册IOutboxPublisher")
+	}
+	if err := receiver.ensureOutboxTable(); err != nil {
+		return err
+	}
+
+	if options.PollInterval <= 0 {
+		options.PollInterval = time.Second
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = 100
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 10
+	}
+	if options.Backoff <= 0 {
+		options.Backoff = time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(options.PollInterval)
+		defer ticker.Stop()
+		var failures int // 连续失败的次数，用于退避
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// 一直有消息时，连续投递，不等待下一次轮询
+				for ctx.Err() == nil {
+					var count int
+					var err error
+					if exp := exception.TryCatch(func() {
+						count, err = receiver.dispatchOutbox(publisher, options)
+					}); exp != nil {
+						err = exp
+					}
+					if err != nil {
+						// 数据库出错时，退避后再轮询，避免持续重试
+						failures++
+						flog.Warningf("发件箱投递失败：%s", err.Error())
+						select {
+						case <-ctx.Done():
+						case <-time.After(outboxBackoff(options.Backoff, failures)):
+						}
+						break
+					}
+					failures = 0
+					if count < options.BatchSize {
+						break
+					}
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// 锁定一批待投递的消息，逐条交给发布者，返回本批的数量（事务失败时返回0和错误）
+// 支持跳过锁定行的数据库：发布在持有行锁的事务中进行，其它实例会跳过这批消息，直到事务结束
+// 其它数据库：逐条通过更新lease_until认领消息，只投递认领成功的
+func (receiver *internalContext) dispatchOutbox(publisher IOutboxPublisher, options OutboxOptions) (int, error) {
+	db, err := openPool(receiver.dbConfig)
+	if err != nil {
+		return 0, err
+	}
+
+	var lockTable string
+	switch receiver.dbConfig.DataType {
+	case "mysql", "postgresql", "postgres":
+		lockTable = outboxTableName
+	case "sqlserver", "mssql":
+		lockTable = outboxTableName + " WITH (UPDLOCK, READPAST, ROWLOCK)"
+	default:
+		return receiver.dispatchOutboxByLease(db.Session(&gorm.Session{}), publisher, options)
+	}
+
+	var lst []outboxPO
+	err = db.Session(&gorm.Session{}).Transaction(func(tx *gorm.DB) error {
+		query := tx.Table(lockTable).Where("key_name = ? and status = ? and next_at <= ?", receiver.dbConfig.keyName, outboxStatusPending, time.Now())
+		if lockTable == outboxTableName {
+			// 多个实例同时投递时，跳过其它实例已锁定的消息
+			query = query.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked})
+		}
+		if err := query.Order("id").Limit(options.BatchSize).Find(&lst).Error; err != nil {
+			return err
+		}
+		return publishOutbox(tx, publisher, options, lst)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(lst), nil
+}
+
+// 不支持跳过锁定行的数据库：先查出候选消息，再逐条认领（lease_until已过期才能认领成功）
+// 认领是自动提交的单条更新，投递者异常退出时，租约到期后由其它实例重新投递
+func (receiver *internalContext) dispatchOutboxByLease(db *gorm.DB, publisher IOutboxPublisher, options OutboxOptions) (int, error) {
+	now := time.Now()
+	var candidates []outboxPO
+	if err := db.Table(outboxTableName).Where("key_name = ? and status = ? and next_at <= ? and (lease_until is null or lease_until < ?)", receiver.dbConfig.keyName, outboxStatusPending, now, now).Order("id").Limit(options.BatchSize).Find(&candidates).Error; err != nil {
+		return 0, err
+	}
+
+	var lst []outboxPO
+	for _, po := range candidates {
+		result := db.Table(outboxTableName).Where("id = ? and status = ? and (lease_until is null or lease_until < ?)", po.Id, outboxStatusPending, now).Update("lease_until", now.Add(outboxLeaseDuration))
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		// 未更新到行时，说明已被其它实例认领
+		if result.RowsAffected == 1 {
+			lst = append(lst, po)
+		}
+	}
+	if err := publishOutbox(db, publisher, options, lst); err != nil {
+		return 0, err
+	}
+	// 按候选数量返回，让轮询在候选满批时继续
+	return len(candidates), nil
+}
+
+// 逐条发布消息，并更新投递结果（同时释放租约）
+func publishOutbox(db *gorm.DB, publisher IOutboxPublisher, options OutboxOptions, lst []outboxPO) error {
+	if len(lst) == 0 {
+		return nil
+	}
+
+	traceHand := trace.Manager().TraceHand(fmt.Sprintf("发件箱投递：%d条", len(lst)))
+	var failCount int
+	for _, po := range lst {
+		var publishErr error
+		if exp := exception.TryCatch(func() {
+			publishErr = publisher.Publish(OutboxMessage{Id: po.Id, Topic: po.Topic, Payload: po.Payload, Attempts: po.Attempts, CreateAt: po.CreateAt})
+		}); exp != nil {
+			publishErr = exp
+		}
+		updates := map[string]any{"attempts": po.Attempts + 1, "lease_until": time.Time{}}
+		if publishErr == nil {
+			updates["status"] = outboxStatusSent
+			updates["sent_at"] = time.Now()
+		} else {
+			failCount++
+			updates["last_error"] = truncateString(publishErr.Error(), 512)
+			updates["next_at"] = time.Now().Add(outboxBackoff(options.Backoff, po.Attempts+1))
+			if po.Attempts+1 >= options.MaxAttempts {
+				updates["status"] = outboxStatusFailed
+			}
+		}
+		if err := db.Table(outboxTableName).Where("id = ?", po.Id).Updates(updates).Error; err != nil {
+			traceHand.End(err)
+			return err
+		}
+	}
+	if failCount > 0 {
+		traceHand.End(fmt.Errorf("投递失败%d条", failCount))
+	} else {
+		traceHand.End(nil)
+	}
+	return nil
+}
+
+// 第attempts次失败后的等待时间（最长1小时）
+func outboxBackoff(backoff time.Duration, attempts int) time.Duration {
+	if attempts > 12 {
+		return time.Hour
+	}
+	if delay := backoff << (attempts - 1); delay < time.Hour {
+		return delay
+	}
+	return time.Hour
+}
+
+// 截断字符串，避免超出字段长度
+func truncateString(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen])
+}
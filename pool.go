@@ -0,0 +1,133 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/farseer-go/fs/flog"
+	"gorm.io/gorm"
+)
+
+// 连接池默认的最大连接数
+const defaultPoolMaxSize = 10
+
+// 检查并补足最小连接数的间隔
+const poolWarmInterval = 30 * time.Second
+
+// 连接池配置（已处理默认值）
+// Database配置：PoolMaxSize=20,PoolMaxIdle=20,PoolMinSize=5,PoolMaxLifetime=3600,PoolMaxIdleTime=300
+type poolSettings struct {
+	maxOpen     int           // 最大连接数
+	maxIdle     int           // 最大空闲连接数
+	minSize     int           // 最小连接数（启动后预先创建，并定时补足）
+	maxLifetime time.Duration // 连接的最长使用时间，0表示不限制
+	maxIdleTime time.Duration // 连接的最长空闲时间，0表示不限制
+}
+
+// 获取连接池配置
+func (receiver *dbConfig) getPoolSettings() poolSettings {
+	settings := poolSettings{
+		maxOpen: receiver.PoolMaxSize,
+		maxIdle: receiver.PoolMaxIdle,
+		minSize: receiver.PoolMinSize,
+	}
+	if settings.maxOpen <= 0 {
+		settings.maxOpen = defaultPoolMaxSize
+	}
+	// 默认空闲连接数等于最大连接数（保持长连接），避免连接频繁创建和销毁带来的性能损耗
+	if settings.maxIdle <= 0 || settings.maxIdle > settings.maxOpen {
+		settings.maxIdle = settings.maxOpen
+	}
+	// 超过最大空闲数的连接归还时会被关闭，无法保持
+	if settings.minSize > settings.maxIdle {
+		settings.minSize = settings.maxIdle
+	}
+
+	// 默认值：clickhouse避免每隔几分钟就重建连接，空闲超时防止极端空闲；其它数据库1小时
+	switch {
+	case receiver.PoolMaxLifetime > 0:
+		settings.maxLifetime = time.Duration(receiver.PoolMaxLifetime) * time.Second
+	case receiver.PoolMaxLifetime == 0 && receiver.DataType == "clickhouse":
+		settings.maxLifetime = 10 * time.Minute
+	case receiver.PoolMaxLifetime == 0:
+		settings.maxLifetime = time.Hour
+	}
+	switch {
+	case receiver.PoolMaxIdleTime > 0:
+		settings.maxIdleTime = time.Duration(receiver.PoolMaxIdleTime) * time.Second
+	case receiver.PoolMaxIdleTime == 0 && receiver.DataType == "clickhouse":
+		settings.maxIdleTime = 5 * time.Minute
+	}
+	return settings
+}
+
+// 设置池大小
+func setPool(gormDB *gorm.DB, dbConfig *dbConfig) {
+	sqlDB, _ := gormDB.DB()
+	settings := dbConfig.getPoolSettings()
+	if dbConfig.PoolMinSize > settings.minSize {
+		flog.Warningf("[config.yaml]Database.%s.PoolMinSize=%d，超过了最大空闲连接数%d，按%d处理", dbConfig.displayName(), dbConfig.PoolMinSize, settings.maxIdle, settings.minSize)
+	}
+	sqlDB.SetMaxOpenConns(settings.maxOpen)        // 最大连接数
+	sqlDB.SetMaxIdleConns(settings.maxIdle)        // 最大空闲连接数
+	sqlDB.SetConnMaxLifetime(settings.maxLifetime) // 连接的最长使用时间
+	sqlDB.SetConnMaxIdleTime(settings.maxIdleTime) // 连接的最长空闲时间
+}
+
+// 预先创建最小连接数，并定时补足（连接因超时、出错被关闭后）
+// 连接池从databaseConn中移除或被替换后，停止补足
+func warmPool(gormDB *gorm.DB, dbConfig *dbConfig) {
+	settings := dbConfig.getPoolSettings()
+	if settings.minSize <= 0 || dbConfig.keyName == "" {
+		return
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return
+	}
+
+	go func() {
+		fillPool(sqlDB, dbConfig.displayName(), settings.minSize)
+		ticker := time.NewTicker(poolWarmInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			// 连接池已被替换或关闭
+			if !isActivePool(dbConfig.keyName, gormDB) {
+				return
+			}
+			fillPool(sqlDB, dbConfig.displayName(), settings.minSize)
+		}
+	}()
+}
+
+// 补足连接池的最小连接数：同时占用不足的连接数，再归还成为空闲连接
+func fillPool(sqlDB *sql.DB, name string, minSize int) {
+	lack := minSize - sqlDB.Stats().OpenConnections
+	if lack <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conns := make([]*sql.Conn, 0, lack)
+	defer func() {
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}()
+	for i := 0; i < lack; i++ {
+		conn, err := sqlDB.Conn(ctx)
+		if err == nil {
+			err = conn.PingContext(ctx)
+		}
+		if err != nil {
+			if conn != nil {
+				_ = conn.Close()
+			}
+			flog.Warningf("[config.yaml]Database.%s 补足最小连接数失败：%s", name, err.Error())
+			return
+		}
+		conns = append(conns, conn)
+	}
+}
@@ -0,0 +1,173 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/farseer-go/fs/container"
+	"github.com/farseer-go/fs/exception"
+	"github.com/farseer-go/fs/flog"
+)
+
+// 检查连接池、上报统计的间隔
+const poolStatsInterval = 10 * time.Second
+
+// 连接池平均等待时间默认超过多少毫秒时提示
+const defaultPoolWaitWarnMs = 100
+
+// PoolStats 连接池的统计
+type PoolStats struct {
+	KeyName           string        // 上下文配置名（config.yaml中的Database节点名），动态连接为脱敏后的连接字符串
+	MaxOpen           int           // 最大连接数
+	Open              int           // 当前的连接数（使用中+空闲）
+	InUse             int           // 使用中的连接数
+	Idle              int           // 空闲的连接数
+	WaitCount         int64         // 累计等待连接的次数
+	WaitDuration      time.Duration // 累计等待连接的时间
+	MaxIdleClosed     int64         // 累计因超过最大空闲数被关闭的连接数
+	MaxIdleTimeClosed int64         // 累计因超过最长空闲时间被关闭的连接数
+	MaxLifetimeClosed int64         // 累计因超过最长使用时间被关闭的连接数
+}
+
+// String 用于健康检查的输出
+func (receiver PoolStats) String() string {
+	return fmt.Sprintf("open=%d/%d inUse=%d idle=%d wait=%d(%s)", receiver.Open, receiver.MaxOpen, receiver.InUse, receiver.Idle, receiver.WaitCount, receiver.WaitDuration.Round(time.Millisecond))
+}
+
+// IPoolStatsReporter 连接池统计的上报（如Prometheus），由业务方注册到容器，每10秒上报一次
+type IPoolStatsReporter interface {
+	// Report 上报所有连接池的统计
+	Report(stats []PoolStats)
+}
+
+// 转换sql.DBStats
+func newPoolStats(name string, stats sql.DBStats) PoolStats {
+	return PoolStats{
+		KeyName:           name,
+		MaxOpen:           stats.MaxOpenConnections,
+		Open:              stats.OpenConnections,
+		InUse:             stats.InUse,
+		Idle:              stats.Idle,
+		WaitCount:         stats.WaitCount,
+		WaitDuration:      stats.WaitDuration,
+		MaxIdleClosed:     stats.MaxIdleClosed,
+		MaxIdleTimeClosed: stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed: stats.MaxLifetimeClosed,
+	}
+}
+
+// 获取所有已打开的连接池
+func getPools() []*databasePool {
+	lock.Lock()
+	defer lock.Unlock()
+	pools := make([]*databasePool, 0, len(databaseConn))
+	for _, pool := range databaseConn {
+		pools = append(pools, pool)
+	}
+	return pools
+}
+
+// GetPoolStats 获取所有已打开的连接池的统计，按KeyName排序
+func GetPoolStats() []PoolStats {
+	var lst []PoolStats
+	for _, pool := range getPools() {
+		if sqlDB, err := pool.gormDB.DB(); err == nil {
+			lst = append(lst, newPoolStats(pool.dbConfig.displayName(), sqlDB.Stats()))
+		}
+	}
+	sort.Slice(lst, func(i, j int) bool { return lst[i].KeyName < lst[j].KeyName })
+	return lst
+}
+
+// PoolStats 获取当前上下文连接池的统计
+func (receiver *internalContext) PoolStats() (PoolStats, error) {
+	gormDB, err := openPool(receiver.dbConfig)
+	if err != nil {
+		return PoolStats{}, err
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return PoolStats{}, err
+	}
+	return newPoolStats(receiver.dbConfig.displayName(), sqlDB.Stats()), nil
+}
+
+var monitorPoolStop chan struct{} // 连接池定时检查的停止信号，未启动时为nil（由lock保护）
+
+// 启动连接池的定时检查（只启动一次），closeAllPools时停止
+func startPoolMonitor() {
+	lock.Lock()
+	defer lock.Unlock()
+	if monitorPoolStop != nil {
+		return
+	}
+	monitorPoolStop = make(chan struct{})
+	go monitorPools(monitorPoolStop)
+	go evictIdlePools(monitorPoolStop)
+}
+
+// 停止连接池的定时检查，调用方需持有lock
+func stopPoolMonitor() {
+	if monitorPoolStop != nil {
+		close(monitorPoolStop)
+		monitorPoolStop = nil
+	}
+}
+
+// 定时检查连接池：平均等待时间超过PoolWaitWarnMs时提示（连接池饱和），并上报统计
+func monitorPools(stop chan struct{}) {
+	ticker := time.NewTicker(poolStatsInterval)
+	defer ticker.Stop()
+	lastStats := make(map[string]PoolStats)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		pools := getPools()
+		var lst []PoolStats
+		currentStats := make(map[string]PoolStats, len(pools))
+		for _, pool := range pools {
+			sqlDB, err := pool.gormDB.DB()
+			if err != nil {
+				continue
+			}
+			stats := newPoolStats(pool.dbConfig.displayName(), sqlDB.Stats())
+			lst = append(lst, stats)
+			currentStats[pool.dbConfig.keyName] = stats
+
+			// 本次间隔内的平均等待时间
+			last := lastStats[pool.dbConfig.keyName]
+			waitCount := stats.WaitCount - last.WaitCount
+			if waitCount <= 0 {
+				continue
+			}
+			avgWait := (stats.WaitDuration - last.WaitDuration) / time.Duration(waitCount)
+			if warnMs := pool.dbConfig.getPoolWaitWarnMs(); warnMs > 0 && avgWait > time.Duration(warnMs)*time.Millisecond {
+				flog.Warningf("[config.yaml]Database.%s 连接池已饱和，最近%s内等待连接%d次，平均等待%s，请检查慢SQL或调大PoolMaxSize。%s", stats.KeyName, poolStatsInterval, waitCount, avgWait.Round(time.Millisecond), stats.String())
+			}
+		}
+		lastStats = currentStats
+
+		if len(lst) > 0 && container.IsRegister[IPoolStatsReporter]() {
+			sort.Slice(lst, func(i, j int) bool { return lst[i].KeyName < lst[j].KeyName })
+			if err := exception.TryCatch(func() {
+				container.Resolve[IPoolStatsReporter]().Report(lst)
+			}); err != nil {
+				flog.Warningf("上报连接池统计时出现异常：%s", err.Error())
+			}
+		}
+	}
+}
+
+// 连接池平均等待时间超过多少毫秒时提示
+func (receiver *dbConfig) getPoolWaitWarnMs() int {
+	if receiver.PoolWaitWarnMs == 0 {
+		return defaultPoolWaitWarnMs
+	}
+	return receiver.PoolWaitWarnMs
+}
@@ -0,0 +1,58 @@
+package data
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/farseer-go/fs/container"
+)
+
+// ConnectionInfo 从连接字符串中解析出的连接信息，不包含密码
+type ConnectionInfo struct {
+	User     string // 用户名
+	Host     string // 主机
+	Port     int    // 端口，连接字符串中未指定时为0
+	Database string // 数据库名称（sqlite为数据库文件）
+	TLS      bool   // 是否使用TLS加密连接
+}
+
+// String 脱敏后的连接信息：user@host:port/db
+func (receiver ConnectionInfo) String() string {
+	var b strings.Builder
+	if receiver.User != "" {
+		b.WriteString(receiver.User + "@")
+	}
+	b.WriteString(receiver.Host)
+	if receiver.Port > 0 {
+		b.WriteString(":" + strconv.Itoa(receiver.Port))
+	}
+	if receiver.Database != "" {
+		if receiver.Host != "" {
+			b.WriteString("/")
+		}
+		b.WriteString(receiver.Database)
+	}
+	return b.String()
+}
+
+// 无法解析连接字符串时，使用的脱敏结果
+const redactedPlaceholder = "******"
+
+// 将连接字符串转换成脱敏后的形式（不包含密码），用于链路追踪、日志、错误信息
+func redactConnectionString(dataType string, connectionString string) string {
+	if connectionString == "" {
+		return ""
+	}
+	if !container.IsRegister[IDataDriver](dataType) {
+		return redactedPlaceholder
+	}
+	parser, isParser := container.Resolve[IDataDriver](dataType).(IConnectionStringParser)
+	if !isParser {
+		return redactedPlaceholder
+	}
+	info, err := parser.ParseConnectionString(connectionString)
+	if err != nil {
+		return redactedPlaceholder
+	}
+	return info.String()
+}
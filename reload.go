@@ -0,0 +1,95 @@
+package data
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/farseer-go/fs/configure"
+	"github.com/farseer-go/fs/container"
+	"github.com/farseer-go/fs/core"
+	"github.com/farseer-go/fs/exception"
+	"github.com/farseer-go/fs/flog"
+)
+
+// 已注册的Database节点配置，用于ApplyDatabaseConfig判断配置是否有变化
+var (
+	configNodes     = make(map[string]string)
+	configNodesLock sync.Mutex
+)
+
+// 注册./config.yaml中的Database节点，支持连接字符串与结构化的配置
+func registerConfigNode(key string, node any) {
+	switch config := node.(type) {
+	case string:
+		if config == "" {
+			panic("[config.yaml]Database." + key + "，配置不正确")
+		}
+		// 注册内部上下文
+		RegisterInternalContext(key, config)
+	case map[string]any:
+		// 结构化的配置
+		RegisterInternalContextWithOptions(key, parseDatabaseOptions(config))
+	default:
+		panic("[config.yaml]Database." + key + "，配置不正确")
+	}
+
+	configNodesLock.Lock()
+	configNodes[key] = fmt.Sprintf("%v", node) // map按key排序输出，可直接比较
+	configNodesLock.Unlock()
+}
+
+// ApplyDatabaseConfig 按内存中的配置（configure.GetSubNodes("Database")）重新注册Database节点
+// 注意：不会重新读取配置文件，fs的配置只在启动时（configure.InitConfig）读取一次
+// 运行时变更配置时，先通过configure.SetDefault更新Database节点（配置文件中不存在的节点），或直接调用RegisterInternalContext
+// 配置文件的变化由Data.ConfigWatchSeconds开启自动检查（见configWatcher.go）
+// 配置有变化的节点切换到新的连接池，旧的连接池等进行中的查询完成后关闭（最多等待DrainSeconds）
+// 已删除的节点移除注册并关闭连接池；配置有误的节点继续使用旧的配置
+func ApplyDatabaseConfig() {
+	applyDatabaseNodes(configure.GetSubNodes("Database"))
+}
+
+// 按nodes重新注册Database节点：有变化的重新注册，已删除的移除注册
+func applyDatabaseNodes(nodes map[string]any) {
+	configNodesLock.Lock()
+	changed := make(map[string]any)
+	for key, node := range nodes {
+		if configNodes[key] != fmt.Sprintf("%v", node) {
+			changed[key] = node
+		}
+	}
+	var removed []string
+	for key := range configNodes {
+		if _, exists := nodes[key]; !exists {
+			removed = append(removed, key)
+			delete(configNodes, key)
+		}
+	}
+	configNodesLock.Unlock()
+
+	for key, node := range changed {
+		if err := exception.TryCatch(func() { registerConfigNode(key, node) }); err != nil {
+			flog.Warningf("[config.yaml]Database.%s 重新加载配置失败，继续使用旧的配置：%s", key, err.Error())
+		}
+	}
+	for _, key := range removed {
+		unregisterInternalContext(key)
+	}
+}
+
+// 移除已注册的上下文，并关闭连接池
+func unregisterInternalContext(key string) {
+	if container.IsRegister[core.ITransaction](key) {
+		container.Remove[core.ITransaction](key)
+	}
+	if container.IsRegister[core.IHealthCheck]("db_" + key) {
+		container.Remove[core.IHealthCheck]("db_" + key)
+	}
+
+	lock.Lock()
+	pool, exists := databaseConn[key]
+	delete(databaseConn, key)
+	lock.Unlock()
+	if exists {
+		go drainPool(pool, pool.dbConfig.getDrainTimeout())
+	}
+}
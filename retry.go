@@ -0,0 +1,122 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/farseer-go/fs/flog"
+	"github.com/farseer-go/fs/trace"
+)
+
+// 默认重试的错误类型
+const defaultRetryOn = "deadlock|serialization|connection"
+
+// 默认首次重试前的等待时间（毫秒）
+const defaultRetryBackoff = 100
+
+// 重试策略中可配置的错误类型
+var retryErrors = map[string]error{
+	"deadlock":      ErrDeadlock,
+	"serialization": ErrSerialization,
+	"connection":    ErrConnection, // 包含clickhouse的code: 101
+	"locktimeout":   ErrLockTimeout,
+	"timeout":       ErrTimeout,
+}
+
+// 重试策略
+// Database配置：RetryMaxAttempts=3,RetryBackoff=100,RetryOn=deadlock|serialization|connection
+type retryPolicy struct {
+	maxAttempts int           // 最多执行的次数（包含第一次）
+	backoff     time.Duration // 首次重试前的等待时间，之后每次翻倍
+	retryOn     []error       // 需要重试的错误类型
+}
+
+// 获取重试策略
+func (receiver *dbConfig) getRetryPolicy() retryPolicy {
+	policy := retryPolicy{
+		maxAttempts: receiver.RetryMaxAttempts,
+		backoff:     time.Duration(receiver.RetryBackoff) * time.Millisecond,
+	}
+	if policy.backoff <= 0 {
+		policy.backoff = defaultRetryBackoff * time.Millisecond
+	}
+
+	retryOn := receiver.RetryOn
+	if retryOn == "" {
+		retryOn = defaultRetryOn
+	}
+	for _, name := range strings.Split(retryOn, "|") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if err, exists := retryErrors[name]; exists {
+			policy.retryOn = append(policy.retryOn, err)
+		} else {
+			flog.Warningf("[config.yaml]Database.%s.RetryOn，不支持的错误类型：%s", receiver.displayName(), name)
+		}
+	}
+	return policy
+}
+
+// 是否需要重试
+func (receiver retryPolicy) canRetry(err error) bool {
+	for _, retryErr := range receiver.retryOn {
+		if errors.Is(err, retryErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// 第attempt次重试前的等待时间（指数退避，并加上随机抖动，避免多个死锁的事务同时重试）
+func (receiver retryPolicy) delay(attempt int) time.Duration {
+	delay := receiver.backoff << (attempt - 1)
+	return delay + time.Duration(rand.Int63n(int64(receiver.backoff)/2+1))
+}
+
+// 按重试策略执行fn，attempt从1开始
+// 未配置RetryMaxAttempts时，只执行一次；不论是否重试，连接异常时都会清理连接池中的脏连接
+func (receiver *dbConfig) retry(name string, fn func(attempt int) error) error {
+	policy := receiver.getRetryPolicy()
+
+	err := receiver.translateError(fn(1))
+	for attempt := 2; ; attempt++ {
+		receiver.flushOnConnectionError(err)
+		if attempt > policy.maxAttempts || !policy.canRetry(err) {
+			return err
+		}
+		time.Sleep(policy.delay(attempt - 1))
+
+		traceHand := trace.Manager().TraceHand(fmt.Sprintf("重试%s（第%d次），原因：%s", name, attempt-1, err.Error()))
+		err = receiver.translateError(fn(attempt))
+		traceHand.End(err)
+	}
+}
+
+// 连接异常时，连接池中可能有脏连接（如clickhouse的code: 101，TCP缓冲区残留了数据），清理掉空闲的连接
+// 下次执行（重试，或由MQ重新投递）时会获取到全新的、干净的连接
+func (receiver *dbConfig) flushOnConnectionError(err error) {
+	if errors.Is(err, ErrConnection) {
+		flog.Warningf("[config.yaml]Database.%s 连接异常，关闭所有空闲连接：%s", receiver.displayName(), err.Error())
+		receiver.flushIdleConnections()
+	}
+}
+
+// 关闭连接池中所有空闲的连接，下次执行时会获取到全新的、干净的连接
+func (receiver *dbConfig) flushIdleConnections() {
+	gormDB, err := openPool(receiver)
+	if err != nil {
+		return
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return
+	}
+	sqlDB.SetMaxIdleConns(0) // 不保留任何空闲连接
+	// 恢复配置
+	sqlDB.SetMaxIdleConns(receiver.getPoolSettings().maxIdle)
+}
@@ -0,0 +1,181 @@
+package data
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/farseer-go/fs/container"
+	"gorm.io/gorm"
+)
+
+// 密钥占位符：${ENV:DB_PASSWORD}、${FILE:/run/secrets/db}、${VAULT:db/password}
+var secretPlaceholder = regexp.MustCompile(`\$\{([A-Za-z][A-Za-z0-9_]*):([^}]+)\}`)
+
+// ISecretProvider 密钥提供者，解析配置中的 ${NAME:key} 占位符
+// 注册到容器时的名称为占位符的前缀（小写），内置了env、file，可注册自定义的实现（如vault）
+type ISecretProvider interface {
+	// GetSecret 获取key对应的密钥
+	GetSecret(key string) (string, error)
+}
+
+// 从环境变量中获取密钥：${ENV:DB_PASSWORD}
+type envSecretProvider struct{}
+
+func (receiver *envSecretProvider) GetSecret(key string) (string, error) {
+	val, exists := os.LookupEnv(key)
+	if !exists {
+		return "", fmt.Errorf("环境变量%s不存在", key)
+	}
+	return val, nil
+}
+
+// 从文件中获取密钥（如docker、k8s的secret）：${FILE:/run/secrets/db}
+type fileSecretProvider struct{}
+
+func (receiver *fileSecretProvider) GetSecret(key string) (string, error) {
+	content, err := os.ReadFile(key)
+	if err != nil {
+		return "", err
+	}
+	// 去掉文件末尾的换行
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// 是否包含密钥占位符
+func hasSecret(val string) bool {
+	return strings.Contains(val, "${") && secretPlaceholder.MatchString(val)
+}
+
+// 将密钥占位符替换成密钥
+func resolveSecrets(val string) (string, error) {
+	if !hasSecret(val) {
+		return val, nil
+	}
+
+	var err error
+	resolved := secretPlaceholder.ReplaceAllStringFunc(val, func(placeholder string) string {
+		if err != nil {
+			return placeholder
+		}
+		match := secretPlaceholder.FindStringSubmatch(placeholder)
+		name := strings.ToLower(match[1])
+		if !container.IsRegister[ISecretProvider](name) {
+			err = fmt.Errorf("未注册密钥提供者ISecretProvider：%s", name)
+			return placeholder
+		}
+		var secret string
+		if secret, err = container.Resolve[ISecretProvider](name).GetSecret(match[2]); err != nil {
+			err = fmt.Errorf("获取密钥%s失败：%w", placeholder, err)
+			return placeholder
+		}
+		return secret
+	})
+	return resolved, err
+}
+
+// 结构化配置中是否包含密钥占位符
+func (receiver DatabaseOptions) hasSecret() bool {
+	if hasSecret(receiver.Host) || hasSecret(receiver.User) || hasSecret(receiver.Password) || hasSecret(receiver.Database) {
+		return true
+	}
+	for _, val := range receiver.Options {
+		if hasSecret(val) {
+			return true
+		}
+	}
+	return false
+}
+
+// 将结构化配置中的密钥占位符替换成密钥
+func (receiver DatabaseOptions) resolveSecrets() (DatabaseOptions, error) {
+	var err error
+	for _, field := range []*string{&receiver.Host, &receiver.User, &receiver.Password, &receiver.Database} {
+		if *field, err = resolveSecrets(*field); err != nil {
+			return receiver, err
+		}
+	}
+	if len(receiver.Options) > 0 {
+		options := make(map[string]string, len(receiver.Options))
+		for key, val := range receiver.Options {
+			if options[key], err = resolveSecrets(val); err != nil {
+				return receiver, err
+			}
+		}
+		receiver.Options = options
+	}
+	return receiver, nil
+}
+
+// 是否使用了密钥占位符
+func (receiver *dbConfig) hasSecret() bool {
+	return receiver.secretOptions != nil || hasSecret(receiver.ConnectionString)
+}
+
+// 获取替换了密钥的连接字符串（ConnectionString中保留的是占位符）
+func (receiver *dbConfig) resolveConnectionString() (string, error) {
+	if receiver.secretOptions != nil {
+		options, err := receiver.secretOptions.resolveSecrets()
+		if err != nil {
+			return "", err
+		}
+		return receiver.createConnectionString(options)
+	}
+	return resolveSecrets(receiver.ConnectionString)
+}
+
+// secretConnector 每次新建连接时重新解析密钥，连接因超时被回收后，新的连接可以使用轮换后的密钥
+type secretConnector struct {
+	dbConfig  *dbConfig
+	driver    driver.Driver
+	tlsDriver ITLSDriver  // 配置了TLS时，由驱动创建连接器
+	tlsConfig *tls.Config // TLS配置
+}
+
+func (receiver *secretConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	connectionString, err := receiver.dbConfig.resolveConnectionString()
+	if err != nil {
+		return nil, err
+	}
+	if receiver.tlsConfig != nil {
+		connector, err := receiver.tlsDriver.TLSConnector(connectionString, receiver.tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		return connector.Connect(ctx)
+	}
+	if driverContext, isDriverContext := receiver.driver.(driver.DriverContext); isDriverContext {
+		connector, err := driverContext.OpenConnector(connectionString)
+		if err != nil {
+			return nil, err
+		}
+		return connector.Connect(ctx)
+	}
+	return receiver.driver.Open(connectionString)
+}
+
+func (receiver *secretConnector) Driver() driver.Driver {
+	return receiver.driver
+}
+
+// 使用了密钥占位符时，将连接池替换成每次新建连接都重新解析密钥的连接池
+func useSecretConnector(gormDB *gorm.DB, dbConfig *dbConfig) {
+	// 配置了TLS时，打开数据库时已使用secretConnector
+	if !dbConfig.hasSecret() || dbConfig.getTLSOptions().enabled() {
+		return
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return
+	}
+	secretDB := sql.OpenDB(&secretConnector{dbConfig: dbConfig, driver: sqlDB.Driver()})
+	gormDB.ConnPool = secretDB
+	gormDB.Statement.ConnPool = secretDB
+	// 打开数据库时创建的连接，使用的是当时的密钥，不再需要
+	_ = sqlDB.Close()
+}
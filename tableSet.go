@@ -1,7 +1,10 @@
 package data
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"strings"
 	"time"
@@ -11,6 +14,7 @@ import (
 	"github.com/farseer-go/fs/container"
 	"github.com/farseer-go/fs/flog"
 	"github.com/farseer-go/fs/parse"
+	"github.com/farseer-go/fs/trace"
 	"github.com/farseer-go/fs/types"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -39,6 +43,12 @@ type TableSet[Table any] struct {
 	limit      int                          // 限制数量
 	offset     int                          // 偏移数量
 	err        error                        // 错误
+	// 回写数据库生成的值
+	useReturning bool     // 是否回写
+	returning    []string // 回写字段（为空时回写所有字段）
+	// 多租户
+	migrateParam map[string]string // 建表参数，租户首次使用时迁移表结构
+	tenant       *tenant           // 当前的租户（创建Session时解析一次）
 }
 
 // where条件
@@ -51,6 +61,7 @@ type whereQuery struct {
 func (receiver *TableSet[Table]) Init(dbContext *internalContext, param map[string]string) {
 	//receiver.dbContext = dbContext.GetInternalContext()
 	receiver.dbContext = dbContext
+	receiver.migrateParam = param
 	receiver.GetPrimaryName()
 	// 表名
 	if name, exists := param["name"]; exists {
@@ -177,6 +188,7 @@ func (receiver *TableSet[Table]) setDbContext(getInternalContext IGetInternalCon
 		tableName:    receiver.tableName,
 		primaryName:  receiver.primaryName,
 		nameReplacer: receiver.nameReplacer,
+		migrateParam: receiver.migrateParam,
 	}
 }
 
@@ -184,24 +196,16 @@ func (receiver *TableSet[Table]) setDbContext(getInternalContext IGetInternalCon
 func (receiver *TableSet[Table]) getOrCreateSession() *TableSet[Table] {
 	if receiver.layer == 0 {
 		// 先从上下文中读取事务
-		gormDB := routineOrmClient[receiver.dbContext.dbConfig.keyName].Get()
+		gormDB := getRoutineOrmClient(receiver.dbContext.dbConfig.keyName).Get()
 		useTransaction := gormDB == nil
-		// 上下文没有开启事务
-		if useTransaction {
-			if gormDB, receiver.err = open(receiver.dbContext.dbConfig); receiver.err == nil {
-				if len(receiver.tableName) > 0 {
-					gormDB = gormDB.Table(receiver.tableName)
-				} else {
-					//var t Table
-					gormDB = gormDB.Session(&gorm.Session{ // .Model(&t)
-						SkipDefaultTransaction: gormDB.SkipDefaultTransaction,
-						Logger:                 gormDB.Logger,
-					})
-				}
-			}
-		} else {
-			if len(receiver.tableName) > 0 {
-				gormDB = gormDB.Table(receiver.tableName)
+		// 每个Session只解析一次租户
+		var current *tenant
+		if current, receiver.err = receiver.dbContext.dbConfig.getTenant(); receiver.err == nil {
+			// 上下文没有开启事务
+			if useTransaction {
+				gormDB, receiver.err = receiver.openClient(current)
+			} else {
+				gormDB, receiver.err = receiver.routeTenant(gormDB, current)
 			}
 		}
 
@@ -219,13 +223,55 @@ func (receiver *TableSet[Table]) getOrCreateSession() *TableSet[Table] {
 			whereList:      collections.NewList[whereQuery](),
 			orderList:      collections.NewListAny(),
 			primaryName:    receiver.primaryName,
+			migrateParam:   receiver.migrateParam,
+			tenant:         current,
 		}
 	}
 	return receiver
 }
 
+// 从连接池中获取ormClient（不在事务中），TenantDatabase时使用租户的数据库
+func (receiver *TableSet[Table]) openClient(current *tenant) (*gorm.DB, error) {
+	gormDB, err := openPool(current.getDbConfig(receiver.dbContext.dbConfig))
+	if err != nil {
+		return nil, err
+	}
+	if len(receiver.tableName) == 0 {
+		//var t Table
+		gormDB = gormDB.Session(&gorm.Session{ // .Model(&t)
+			SkipDefaultTransaction: gormDB.SkipDefaultTransaction,
+			Logger:                 gormDB.Logger,
+		})
+	}
+	return receiver.routeTenant(gormDB, current)
+}
+
+// 执行查询，不在事务中时，按重试策略重试
+// 重试时重新获取ormClient，链式的筛选条件暂存在selectList、whereList等字段中，getClient会重新设置
+func (receiver *TableSet[Table]) query(name string, fn func(client *gorm.DB) error) error {
+	session := receiver.getOrCreateSession()
+	if session.err != nil {
+		return session.err
+	}
+	// 事务中的语句失败后，整个事务都需要回滚，不能单独重试
+	if !session.useTransaction {
+		err := session.dbContext.dbConfig.translateError(fn(session.getClient()))
+		session.dbContext.dbConfig.flushOnConnectionError(err)
+		return err
+	}
+	return session.dbContext.dbConfig.retry(name+"："+session.tableName, func(attempt int) error {
+		if attempt > 1 {
+			var err error
+			if session.ormClient, err = session.openClient(session.tenant); err != nil {
+				return err
+			}
+		}
+		return fn(session.getClient())
+	})
+}
+
 func (receiver *TableSet[Table]) getClient() *gorm.DB {
-	receiver.ormClient.InstanceSet("ConnectionString", receiver.dbContext.dbConfig.ConnectionString)
+	receiver.ormClient.InstanceSet("ConnectionString", receiver.dbContext.dbConfig.redacted)
 	receiver.ormClient.InstanceSet("DbName", receiver.dbContext.dbConfig.databaseName)
 
 	// 设置Select
@@ -632,67 +678,169 @@ func (receiver *TableSet[Table]) Offset(offset int) *TableSet[Table] {
 	return session
 }
 
+// InTx 在显式的事务中执行（优先于协程上下文中的事务）
+func (receiver *TableSet[Table]) InTx(tx *Tx) *TableSet[Table] {
+	session := receiver.getOrCreateSession()
+	if tx.dbConfig.ConnectionString != session.dbContext.dbConfig.ConnectionString {
+		session.err = fmt.Errorf("事务对象与表：%s 不属于同一个数据库", session.tableName)
+		return session
+	}
+
+	// 租户解析失败时，不能在事务中不按租户读写
+	if session.err != nil {
+		return session
+	}
+	session.useTransaction = false
+	// 与协程上下文中的事务相同，按租户路由（TenantSchema的表名、TenantSharedTable的租户字段）
+	session.ormClient, session.err = session.routeTenant(tx.gormDB, session.tenant)
+	return session
+}
+
+// Returning 回写数据库生成的值（默认值、触发器计算的字段、UUID等），作用于Insert、InsertList、UpdateReturning、DeleteReturning
+// postgres/sqlite使用RETURNING，sqlserver使用OUTPUT，不支持的数据库（如mysql）按主键回查
+// columns为空时，回写所有字段
+func (receiver *TableSet[Table]) Returning(columns ...string) *TableSet[Table] {
+	session := receiver.getOrCreateSession()
+	session.useReturning = true
+	session.returning = append(session.returning, columns...)
+	return session
+}
+
 // ToList 返回结果集
 func (receiver *TableSet[Table]) ToList() collections.List[Table] {
-	var lst []Table
-	receiver.getOrCreateSession().getClient().Find(&lst)
-	return collections.NewList(lst...)
+	lst, _ := receiver.TryToList()
+	return lst
 }
 
 // Fill 填充结果集
 func (receiver *TableSet[Table]) Fill(dest any, conds ...any) {
-	receiver.getOrCreateSession().getClient().Find(dest, conds...)
+	_ = receiver.TryFill(dest, conds...)
 }
 
 // ToArray 返回结果集
 func (receiver *TableSet[Table]) ToArray() []Table {
-	var lst []Table
-	receiver.getOrCreateSession().getClient().Find(&lst)
+	lst, _ := receiver.TryToArray()
 	return lst
 }
 
 // ToPageList 返回分页结果集
 func (receiver *TableSet[Table]) ToPageList(pageSize int, pageIndex int) collections.PageList[Table] {
-	var count int64
-	client := receiver.getOrCreateSession().getClient()
-	client.Count(&count)
-
-	offset := (pageIndex - 1) * pageSize
-	var lst []Table
-	client.Offset(offset).Limit(pageSize).Find(&lst)
-	return collections.NewPageList(collections.NewList(lst...), count)
+	pageList, _ := receiver.TryToPageList(pageSize, pageIndex)
+	return pageList
 }
 
 // ToEntity 返回单个对象
 func (receiver *TableSet[Table]) ToEntity() Table {
-	var entity Table
-	receiver.getOrCreateSession().getClient().Limit(1).Find(&entity)
+	entity, _, _ := receiver.FindOne()
 	return entity
 }
 
 // Count 返回表中的数量
 func (receiver *TableSet[Table]) Count() int64 {
-	var count int64
-	receiver.getOrCreateSession().getClient().Count(&count)
+	count, _ := receiver.TryCount()
 	return count
 }
 
 // IsExists 是否存在记录
 func (receiver *TableSet[Table]) IsExists() bool {
+	isExists, _ := receiver.TryIsExists()
+	return isExists
+}
+
+// TryToList 返回结果集，查询失败时返回错误
+func (receiver *TableSet[Table]) TryToList() (collections.List[Table], error) {
+	lst, err := receiver.TryToArray()
+	return collections.NewList(lst...), err
+}
+
+// TryFill 填充结果集，查询失败时返回错误
+func (receiver *TableSet[Table]) TryFill(dest any, conds ...any) error {
+	return receiver.query("Fill", func(client *gorm.DB) error {
+		return client.Find(dest, conds...).Error
+	})
+}
+
+// TryToArray 返回结果集，查询失败时返回错误
+func (receiver *TableSet[Table]) TryToArray() ([]Table, error) {
+	var lst []Table
+	err := receiver.query("ToList", func(client *gorm.DB) error {
+		lst = nil
+		return client.Find(&lst).Error
+	})
+	return lst, err
+}
+
+// TryToPageList 返回分页结果集，查询失败时返回错误
+func (receiver *TableSet[Table]) TryToPageList(pageSize int, pageIndex int) (collections.PageList[Table], error) {
+	var count int64
+	var lst []Table
+	err := receiver.query("ToPageList", func(client *gorm.DB) error {
+		if err := client.Count(&count).Error; err != nil {
+			return err
+		}
+		offset := (pageIndex - 1) * pageSize
+		lst = nil
+		return client.Offset(offset).Limit(pageSize).Find(&lst).Error
+	})
+	if err != nil {
+		return collections.NewPageList(collections.NewList[Table](), 0), err
+	}
+	return collections.NewPageList(collections.NewList(lst...), count), nil
+}
+
+// FindOne 返回单个对象，found表示是否找到记录，查询失败时返回错误
+func (receiver *TableSet[Table]) FindOne() (entity Table, found bool, err error) {
+	err = receiver.query("ToEntity", func(client *gorm.DB) error {
+		var po Table
+		result := client.Limit(1).Find(&po)
+		entity, found = po, result.Error == nil && result.RowsAffected > 0
+		return result.Error
+	})
+	return entity, found, err
+}
+
+// TryCount 返回表中的数量，查询失败时返回错误
+func (receiver *TableSet[Table]) TryCount() (int64, error) {
 	var count int64
-	receiver.getOrCreateSession().getClient().Count(&count)
-	return count > 0
+	err := receiver.query("Count", func(client *gorm.DB) error {
+		return client.Count(&count).Error
+	})
+	return count, err
+}
+
+// TryIsExists 是否存在记录，查询失败时返回错误
+func (receiver *TableSet[Table]) TryIsExists() (bool, error) {
+	count, err := receiver.TryCount()
+	return count > 0, err
 }
 
 // Insert 新增记录
 func (receiver *TableSet[Table]) Insert(po *Table) error {
-	result := receiver.getOrCreateSession().getClient().Create(po)
-	return result.Error
+	session := receiver.getOrCreateSession()
+	client := session.getClient()
+	if !session.useReturning {
+		return client.Create(po).Error
+	}
+
+	// 回写数据库生成的值
+	if supportReturning(client.Callback().Create().Clauses) {
+		return client.Clauses(session.returningClause()).Create(po).Error
+	}
+	if err := client.Create(po).Error; err != nil {
+		return err
+	}
+	return session.findByPrimary(po)
 }
 
 // InsertIgnore 新增记录（忽略主键、唯一键存在的记录）
+// 由驱动生成对应方言：mysql为INSERT IGNORE，postgres/sqlite为ON CONFLICT DO NOTHING，sqlserver为MERGE，clickhouse由表引擎去重
+// 方言未覆盖的冲突（如sqlserver的MERGE只按主键匹配，唯一索引冲突时）同样忽略
 func (receiver *TableSet[Table]) InsertIgnore(po *Table) (int64, error) {
-	result := receiver.getOrCreateSession().getClient().Clauses(clause.Insert{Modifier: "IGNORE"}).Create(po)
+	insertIgnore := receiver.dbContext.dbConfig.getInsertIgnore()
+	result := receiver.getOrCreateSession().getClient().Clauses(insertIgnore...).Create(po)
+	if errors.Is(result.Error, ErrDuplicateKey) {
+		return 0, nil
+	}
 	return result.RowsAffected, result.Error
 }
 
@@ -719,27 +867,41 @@ func (receiver *TableSet[Table]) InsertList(lst collections.List[Table], batchSi
 	var result *gorm.DB
 	var rowsAffected int64
 	var err error
+	session := receiver.getOrCreateSession()
 
 	if receiver.dbContext.dbConfig.DataType == "clickhouse" {
-		// 在 ClickHouse 驱动中，这个 Transaction 块不会发送真正的 SQL BEGIN, 它只是在驱动层开启一个 Block 容器，确保执行完后自动触发 Flush
-		session := receiver.getOrCreateSession()
-		err = session.getClient().Transaction(func(tx *gorm.DB) error { // Transaction必须这么使用,否则数据库查不到数据
-			result := tx.CreateInBatches(lst.ToArray(), lst.Count()) // 不能使用batchSize,会出现code: 101, message: Unexpected packet Query received from client
-			if result.Error != nil {
-				return result.Error
-			}
-			rowsAffected = result.RowsAffected
-			return nil
+		// clickhouse的Block在Flush时才整体写入，失败时没有写入任何数据，因此可以按重试策略重试（如code: 101）
+		err = session.query("InsertList", func(client *gorm.DB) error {
+			// 在 ClickHouse 驱动中，这个 Transaction 块不会发送真正的 SQL BEGIN, 它只是在驱动层开启一个 Block 容器，确保执行完后自动触发 Flush
+			return client.Transaction(func(tx *gorm.DB) error { // Transaction必须这么使用,否则数据库查不到数据
+				result := tx.CreateInBatches(lst.ToArray(), lst.Count()) // 不能使用batchSize,会出现code: 101, message: Unexpected packet Query received from client
+				if result.Error != nil {
+					return result.Error
+				}
+				rowsAffected = result.RowsAffected
+				return nil
+			})
 		})
-		// 针对 clickhouse 的 code: 101 错误，清理脏连接
 		if err != nil {
-			receiver.cleanDirtyConnectionOnError(err)
 			return rowsAffected, err
 		}
 	} else {
-		result = receiver.getOrCreateSession().getClient().CreateInBatches(lst.ToArray(), batchSize)
+		client := session.getClient()
+		if session.useReturning && supportReturning(client.Callback().Create().Clauses) {
+			client = client.Clauses(session.returningClause())
+		}
+		result = client.CreateInBatches(lst.ToArray(), batchSize)
 		rowsAffected = result.RowsAffected
 		err = result.Error
+		if err == nil && session.useReturning && !supportReturning(client.Callback().Create().Clauses) {
+			// 不支持RETURNING时，逐条按主键回查（lst.ToArray()返回的是集合底层数组，回查结果会直接写回集合）
+			arr := lst.ToArray()
+			for i := range arr {
+				if err = session.findByPrimary(&arr[i]); err != nil {
+					break
+				}
+			}
+		}
 	}
 
 	return rowsAffected, err
@@ -751,33 +913,140 @@ func (receiver *TableSet[Table]) InsertIgnoreList(lst collections.List[Table], b
 		return 0, nil
 	}
 
-	var result *gorm.DB
 	var rowsAffected int64
 	var err error
+	insertIgnore := receiver.dbContext.dbConfig.getInsertIgnore()
 
 	if receiver.dbContext.dbConfig.DataType == "clickhouse" {
-		// 在 ClickHouse 驱动中，这个 Transaction 块不会发送真正的 SQL BEGIN, 它只是在驱动层开启一个 Block 容器，确保执行完后自动触发 Flush
-		err = receiver.getOrCreateSession().getClient().Clauses(clause.Insert{Modifier: "IGNORE"}).Transaction(func(tx *gorm.DB) error {
-			result := tx.CreateInBatches(lst.ToArray(), lst.Count()) // 不能使用batchSize,会出现code: 101, message: Unexpected packet Query received from client
-			if result.Error != nil {
-				return result.Error
-			}
-			rowsAffected = result.RowsAffected
-			return nil
+		// clickhouse的Block在Flush时才整体写入，失败时没有写入任何数据，因此可以按重试策略重试（如code: 101）
+		err = receiver.query("InsertIgnoreList", func(client *gorm.DB) error {
+			// 在 ClickHouse 驱动中，这个 Transaction 块不会发送真正的 SQL BEGIN, 它只是在驱动层开启一个 Block 容器，确保执行完后自动触发 Flush
+			return client.Clauses(insertIgnore...).Transaction(func(tx *gorm.DB) error {
+				result := tx.CreateInBatches(lst.ToArray(), lst.Count()) // 不能使用batchSize,会出现code: 101, message: Unexpected packet Query received from client
+				if result.Error != nil {
+					return result.Error
+				}
+				rowsAffected = result.RowsAffected
+				return nil
+			})
 		})
-		// 针对 clickhouse 的 code: 101 错误，清理脏连接
-		if err != nil {
-			receiver.cleanDirtyConnectionOnError(err)
-		}
 	} else {
-		result = receiver.getOrCreateSession().getClient().Clauses(clause.Insert{Modifier: "IGNORE"}).CreateInBatches(lst.ToArray(), batchSize)
-		rowsAffected = result.RowsAffected
-		err = result.Error
+		rowsAffected, err = insertIgnoreBatches(receiver.getOrCreateSession().getClient(), insertIgnore, lst.ToArray(), batchSize)
+	}
+
+	return rowsAffected, err
+}
+
+// 分批新增并忽略冲突的记录
+// 方言未覆盖的冲突（如sqlserver的MERGE只按主键匹配，自增主键为0时退化为INSERT，唯一索引冲突时报错），该批逐条新增，跳过冲突的记录
+func insertIgnoreBatches[Table any](client *gorm.DB, insertIgnore []clause.Expression, arr []Table, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = len(arr)
+	}
+	client = client.Session(&gorm.Session{})
+
+	var rowsAffected int64
+	for start := 0; start < len(arr); start += batchSize {
+		end := start + batchSize
+		if end > len(arr) {
+			end = len(arr)
+		}
+		batch := arr[start:end]
+		result := client.Clauses(insertIgnore...).Create(&batch)
+		if result.Error == nil {
+			rowsAffected += result.RowsAffected
+			continue
+		}
+		if !errors.Is(result.Error, ErrDuplicateKey) {
+			return rowsAffected, result.Error
+		}
+
+		for i := range batch {
+			result = client.Clauses(insertIgnore...).Create(&batch[i])
+			if result.Error != nil && !errors.Is(result.Error, ErrDuplicateKey) {
+				return rowsAffected, result.Error
+			}
+			rowsAffected += result.RowsAffected
+		}
+	}
+	return rowsAffected, nil
+}
+
+// BulkLoad 使用数据库原生的导入方式批量写入（适用于大批量导入）
+// source支持：collections.List[Table]、[]Table、chan Table、CsvSource、JsonLinesSource
+// postgres使用COPY FROM STDIN，mysql使用LOAD DATA LOCAL INFILE（需开启local_infile），clickhouse使用原生批量协议，sqlserver使用bulk copy
+// 驱动未实现IBulkLoader、数据库拒绝原生导入、当前处于事务中、或共享表的租户时，退化为分批InsertList
+// 每读取10000条向链路追踪汇报一次进度，并回调progress（可选，原生导入时在读取数据的协程中回调）
+func (receiver *TableSet[Table]) BulkLoad(source any, progress ...func(readCount int64)) (int64, error) {
+	session := receiver.getOrCreateSession()
+	if session.err != nil {
+		return 0, session.err
+	}
+	rows, err := newBulkRows[Table](source)
+	if err != nil {
+		return 0, err
+	}
+
+	traceManager := trace.Manager()
+	traceHand := traceManager.TraceHand("批量导入：" + session.tableName)
+	// 向链路追踪汇报进度，同时回调progress
+	rows.progress = func(readCount int64) {
+		traceManager.TraceHand(fmt.Sprintf("批量导入：%s，已读取%d条", session.tableName, readCount)).End(nil)
+		for _, callback := range progress {
+			callback(readCount)
+		}
 	}
 
+	var rowsAffected int64
+	// useTransaction=true表示上下文没有开启事务，原生导入会使用独立的连接，因此事务中不使用
+	// 共享表的租户需要写入租户字段，由InsertList（TenantPlugin）写入，因此也不使用
+	sharedTable := session.tenant != nil && session.tenant.route.Strategy == TenantSharedTable
+	if bulkLoader, isBulkLoader := session.dbContext.dbConfig.GetDataDriver().(IBulkLoader); isBulkLoader && session.useTransaction && !sharedTable {
+		var sqlDB *sql.DB
+		// ormClient已按租户使用对应的连接池（TenantDatabase），表名加上租户的schema（TenantSchema）
+		if sqlDB, err = session.ormClient.DB(); err == nil {
+			rowsAffected, err = bulkLoader.BulkLoad(sqlDB, session.tenant.tableName(session.tableName), rows.columns(), rows.values)
+			if errors.Is(err, ErrBulkLoadNotSupported) {
+				flog.Warningf("批量导入：%s，%s，退化为分批写入", session.tableName, err.Error())
+				rowsAffected, err = session.bulkInsert(rows)
+			}
+			err = session.dbContext.dbConfig.translateError(err)
+		}
+	} else {
+		rowsAffected, err = session.bulkInsert(rows)
+	}
+	traceHand.End(err)
 	return rowsAffected, err
 }
 
+// 驱动不支持原生导入时，分批使用InsertList写入
+func (receiver *TableSet[Table]) bulkInsert(rows *bulkRows[Table]) (int64, error) {
+	var rowsAffected int64
+	batch := make([]Table, 0, bulkLoadBatchSize)
+	for {
+		po, err := rows.read()
+		if err != nil && err != io.EOF {
+			return rowsAffected, err
+		}
+		if err == nil {
+			batch = append(batch, po)
+		}
+
+		// 凑满一批、或已读完时写入
+		if len(batch) == bulkLoadBatchSize || (err == io.EOF && len(batch) > 0) {
+			count, insertErr := receiver.InsertList(collections.NewList(batch...), len(batch))
+			rowsAffected += count
+			if insertErr != nil {
+				return rowsAffected, insertErr
+			}
+			batch = make([]Table, 0, bulkLoadBatchSize)
+		}
+		if err == io.EOF {
+			return rowsAffected, nil
+		}
+	}
+}
+
 // Expr 对字段做表达式操作
 //
 //	exp: Expr("price", "price * ? + ?", 2, 100)
@@ -827,6 +1096,35 @@ func (receiver *TableSet[Table]) Update(po Table) (int64, error) {
 	return result.RowsAffected, result.Error
 }
 
+// UpdateReturning 修改记录，并返回修改后的记录（回写字段由Returning指定，未指定时返回所有字段）
+// 如果只更新部份字段，需使用Select进行筛选
+func (receiver *TableSet[Table]) UpdateReturning(po Table) (collections.List[Table], error) {
+	session := receiver.getOrCreateSession()
+	mapPO := ToMap(po)
+	client := session.getClient()
+
+	var lst []Table
+	if supportReturning(client.Callback().Update().Clauses) {
+		result := client.Model(&lst).Clauses(session.returningClause()).Updates(mapPO)
+		return collections.NewList(lst...), result.Error
+	}
+
+	// 不支持RETURNING时：先按条件查出主键，修改后再按主键回查
+	lst, err := session.findPrimaryList()
+	if err != nil {
+		return collections.NewList[Table](), err
+	}
+	if result := client.Updates(mapPO); result.Error != nil {
+		return collections.NewList[Table](), result.Error
+	}
+	for i := range lst {
+		if err = session.findByPrimary(&lst[i]); err != nil {
+			break
+		}
+	}
+	return collections.NewList(lst...), err
+}
+
 // UpdateOrInsertByPrimary 记录存在时（根据主键判断）更新，不存在时插入
 func (receiver *TableSet[Table]) UpdateOrInsertByPrimary(po Table) error {
 	return receiver.UpdateOrInsert(po, receiver.primaryName...)
@@ -896,6 +1194,57 @@ func (receiver *TableSet[Table]) Delete() (int64, error) {
 	return result.RowsAffected, result.Error
 }
 
+// DeleteReturning 删除记录，并返回被删除的记录（回写字段由Returning指定，未指定时返回所有字段）
+func (receiver *TableSet[Table]) DeleteReturning() (collections.List[Table], error) {
+	session := receiver.getOrCreateSession()
+	client := session.getClient()
+
+	var lst []Table
+	if supportReturning(client.Callback().Delete().Clauses) {
+		result := client.Clauses(session.returningClause()).Delete(&lst)
+		return collections.NewList(lst...), result.Error
+	}
+
+	// 不支持RETURNING时：先按条件查出记录（SELECT ... FOR UPDATE），再按相同的条件删除，查询与删除在同一个事务中
+	// clickhouse不支持事务和行锁，查询与删除之间的并发修改不会体现在结果中
+	isClickhouse := session.dbContext.dbConfig.DataType == "clickhouse"
+	deleteReturning := func(tx *gorm.DB) error {
+		query, err := session.newConditionClient(tx)
+		if err != nil {
+			return err
+		}
+		if len(session.returning) > 0 {
+			query = query.Select(session.returning)
+		}
+		if !isClickhouse {
+			query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+		}
+		if err = query.Find(&lst).Error; err != nil {
+			return err
+		}
+		del, err := session.newConditionClient(tx)
+		if err != nil {
+			return err
+		}
+		return del.Delete(nil).Error
+	}
+
+	if session.err != nil {
+		return collections.NewList[Table](), session.err
+	}
+	var err error
+	// useTransaction=true表示上下文没有开启事务
+	if session.useTransaction && !isClickhouse {
+		err = session.ormClient.Session(&gorm.Session{NewDB: true}).Transaction(deleteReturning)
+	} else {
+		err = deleteReturning(session.ormClient)
+	}
+	if err != nil {
+		return collections.NewList[Table](), err
+	}
+	return collections.NewList(lst...), nil
+}
+
 // GetMap 获取key value，然后将结果保存到m字段，m字段为map[xx]xxx
 func (receiver *TableSet[Table]) GetMap(m any, keyFieldName string, keyValue string) (int64, error) {
 	result := receiver.getOrCreateSession().getClient().Select(keyFieldName, keyValue)
@@ -928,276 +1277,180 @@ func (receiver *TableSet[Table]) GetMap(m any, keyFieldName string, keyValue str
 
 // GetString 获取单条记录中的单个string类型字段值
 func (receiver *TableSet[Table]) GetString(fieldName string) string {
-	result := receiver.getOrCreateSession().getClient().Select(fieldName).Limit(1)
-	rows, _ := result.Rows()
-	if rows == nil {
-		return ""
-	}
-	defer rows.Close()
-	var val string
-	for rows.Next() {
-		_ = rows.Scan(&val)
-		// ScanRows 方法用于将一行记录扫描至结构体
-		//receiver.ScanRows(rows, &user)
-	}
+	val, _ := receiver.TryGetString(fieldName)
 	return val
 }
 
 // GetStrings 获取string字段的集合
 func (receiver *TableSet[Table]) GetStrings(fieldName string) collections.List[string] {
-	lst := collections.NewList[string]()
-	result := receiver.getOrCreateSession().getClient().Select(fieldName)
-	rows, _ := result.Rows()
-	if rows == nil {
-		return lst
-	}
-	defer rows.Close()
-	var val string
-	for rows.Next() {
-		_ = rows.Scan(&val)
-		lst.Add(val)
-	}
+	lst, _ := receiver.TryGetStrings(fieldName)
 	return lst
 }
 
 // GetInt 获取单条记录中的单个int类型字段值
 func (receiver *TableSet[Table]) GetInt(fieldName string) int {
-	result := receiver.getOrCreateSession().getClient().Select(fieldName).Limit(1)
-	rows, _ := result.Rows()
-	if rows == nil {
-		return 0
-	}
-	defer func() {
-		_ = rows.Close()
-	}()
-	var val int
-	for rows.Next() {
-		_ = rows.Scan(&val)
-	}
+	val, _ := receiver.TryGetInt(fieldName)
 	return val
 }
 
 // GetInts 获取int字段的集合
 func (receiver *TableSet[Table]) GetInts(fieldName string) collections.List[int] {
-	lst := collections.NewList[int]()
-	result := receiver.getOrCreateSession().getClient().Select(fieldName)
-	rows, _ := result.Rows()
-	if rows == nil {
-		return lst
-	}
-	defer rows.Close()
-	var val int
-	for rows.Next() {
-		_ = rows.Scan(&val)
-		lst.Add(val)
-	}
+	lst, _ := receiver.TryGetInts(fieldName)
 	return lst
 }
 
 // GetLong 获取单条记录中的单个int64类型字段值
 func (receiver *TableSet[Table]) GetLong(fieldName string) int64 {
-	result := receiver.getOrCreateSession().getClient().Select(fieldName).Limit(1)
-	rows, _ := result.Rows()
-	if rows == nil {
-		return int64(0)
-	}
-	defer func() {
-		_ = rows.Close()
-	}()
-	var val int64
-	for rows.Next() {
-		_ = rows.Scan(&val)
-	}
+	val, _ := receiver.TryGetLong(fieldName)
 	return val
 }
 
 // GetLongs 获取long字段的集合
 func (receiver *TableSet[Table]) GetLongs(fieldName string) collections.List[int64] {
-	lst := collections.NewList[int64]()
-	result := receiver.getOrCreateSession().getClient().Select(fieldName)
-	rows, _ := result.Rows()
-	if rows == nil {
-		return lst
-	}
-	defer rows.Close()
-	var val int64
-	for rows.Next() {
-		_ = rows.Scan(&val)
-		lst.Add(val)
-	}
+	lst, _ := receiver.TryGetLongs(fieldName)
 	return lst
 }
 
 // GetBool 获取单条记录中的单个bool类型字段值
 func (receiver *TableSet[Table]) GetBool(fieldName string) bool {
-	result := receiver.getOrCreateSession().getClient().Select(fieldName).Limit(1)
-	rows, _ := result.Rows()
-	if rows == nil {
-		return false
-	}
-	defer func() {
-		_ = rows.Close()
-	}()
-	var val bool
-	for rows.Next() {
-		_ = rows.Scan(&val)
-	}
+	val, _ := receiver.TryGetBool(fieldName)
 	return val
 }
 
 // GetBools 获取bool字段的集合
 func (receiver *TableSet[Table]) GetBools(fieldName string) collections.List[bool] {
-	lst := collections.NewList[bool]()
-	result := receiver.getOrCreateSession().getClient().Select(fieldName)
-	rows, _ := result.Rows()
-	if rows == nil {
-		return lst
-	}
-	defer rows.Close()
-	var val bool
-	for rows.Next() {
-		_ = rows.Scan(&val)
-		lst.Add(val)
-	}
+	lst, _ := receiver.TryGetBools(fieldName)
 	return lst
 }
 
 // GetFloat32 获取单条记录中的单个float32类型字段值
 func (receiver *TableSet[Table]) GetFloat32(fieldName string) float32 {
-	result := receiver.getOrCreateSession().getClient().Select(fieldName).Limit(1)
-	rows, _ := result.Rows()
-	if rows == nil {
-		return float32(0)
-	}
-	defer func() {
-		_ = rows.Close()
-	}()
-	var val float32
-	for rows.Next() {
-		_ = rows.Scan(&val)
-	}
+	val, _ := receiver.TryGetFloat32(fieldName)
 	return val
 }
 
 // GetFloat32s 获取float32字段的集合
 func (receiver *TableSet[Table]) GetFloat32s(fieldName string) collections.List[float32] {
-	lst := collections.NewList[float32]()
-	result := receiver.getOrCreateSession().getClient().Select(fieldName)
-	rows, _ := result.Rows()
-	if rows == nil {
-		return lst
-	}
-	defer rows.Close()
-	var val float32
-	for rows.Next() {
-		_ = rows.Scan(&val)
-		lst.Add(val)
-	}
+	lst, _ := receiver.TryGetFloat32s(fieldName)
 	return lst
 }
 
 // GetFloat64 获取单条记录中的单个float64类型字段值
 func (receiver *TableSet[Table]) GetFloat64(fieldName string) float64 {
-	result := receiver.getOrCreateSession().getClient().Select(fieldName).Limit(1)
-	rows, _ := result.Rows()
-	if rows == nil {
-		return float64(0)
-	}
-	defer func() {
-		_ = rows.Close()
-	}()
-	var val float64
-	for rows.Next() {
-		_ = rows.Scan(&val)
-	}
+	val, _ := receiver.TryGetFloat64(fieldName)
 	return val
 }
 
 // GetFloat64s 获取float64字段的集合
 func (receiver *TableSet[Table]) GetFloat64s(fieldName string) collections.List[float64] {
-	lst := collections.NewList[float64]()
-	result := receiver.getOrCreateSession().getClient().Select(fieldName)
-	rows, _ := result.Rows()
-	if rows == nil {
-		return lst
-	}
-	defer rows.Close()
-	var val float64
-	for rows.Next() {
-		_ = rows.Scan(&val)
-		lst.Add(val)
-	}
+	lst, _ := receiver.TryGetFloat64s(fieldName)
 	return lst
 }
 
 // GetDecimal 获取单条记录中的单个decimal.Decimal类型字段值
 func (receiver *TableSet[Table]) GetDecimal(fieldName string) decimal.Decimal {
-	result := receiver.getOrCreateSession().getClient().Select(fieldName).Limit(1)
-	rows, _ := result.Rows()
-	if rows == nil {
-		return decimal.Zero
-	}
-	defer func() {
-		_ = rows.Close()
-	}()
-	var val decimal.Decimal
-	for rows.Next() {
-		_ = rows.Scan(&val)
-	}
+	val, _ := receiver.TryGetDecimal(fieldName)
 	return val
 }
 
 // GetDecimals 获取decimal.Decimal字段的集合
 func (receiver *TableSet[Table]) GetDecimals(fieldName string) collections.List[decimal.Decimal] {
-	lst := collections.NewList[decimal.Decimal]()
-	result := receiver.getOrCreateSession().getClient().Select(fieldName)
-	rows, _ := result.Rows()
-	if rows == nil {
-		return lst
-	}
-	defer rows.Close()
-	var val decimal.Decimal
-	for rows.Next() {
-		_ = rows.Scan(&val)
-		lst.Add(val)
-	}
+	lst, _ := receiver.TryGetDecimals(fieldName)
 	return lst
 }
 
 // GetTime 获取单条记录中的单个time.Time类型字段值
 func (receiver *TableSet[Table]) GetTime(fieldName string) time.Time {
-	result := receiver.getOrCreateSession().getClient().Select(fieldName).Limit(1)
-	rows, _ := result.Rows()
-	if rows == nil {
-		return time.Time{}
-	}
-	defer func() {
-		_ = rows.Close()
-	}()
-	var val time.Time
-	for rows.Next() {
-		_ = rows.Scan(&val)
-	}
+	val, _ := receiver.TryGetTime(fieldName)
 	return val
 }
 
 // GetTimes 获取time.Time字段的集合
 func (receiver *TableSet[Table]) GetTimes(fieldName string) collections.List[time.Time] {
-	lst := collections.NewList[time.Time]()
-	result := receiver.getOrCreateSession().getClient().Select(fieldName)
-	rows, _ := result.Rows()
-	if rows == nil {
-		return lst
-	}
-	defer rows.Close()
-	var val time.Time
-	for rows.Next() {
-		_ = rows.Scan(&val)
-		lst.Add(val)
-	}
+	lst, _ := receiver.TryGetTimes(fieldName)
 	return lst
 }
 
+// TryGetString 获取单条记录中的单个string类型字段值，查询失败时返回错误
+func (receiver *TableSet[Table]) TryGetString(fieldName string) (string, error) {
+	return getValue[string](receiver, fieldName)
+}
+
+// TryGetStrings 获取string字段的集合，查询失败时返回错误
+func (receiver *TableSet[Table]) TryGetStrings(fieldName string) (collections.List[string], error) {
+	return getValues[string](receiver, fieldName)
+}
+
+// TryGetInt 获取单条记录中的单个int类型字段值，查询失败时返回错误
+func (receiver *TableSet[Table]) TryGetInt(fieldName string) (int, error) {
+	return getValue[int](receiver, fieldName)
+}
+
+// TryGetInts 获取int字段的集合，查询失败时返回错误
+func (receiver *TableSet[Table]) TryGetInts(fieldName string) (collections.List[int], error) {
+	return getValues[int](receiver, fieldName)
+}
+
+// TryGetLong 获取单条记录中的单个int64类型字段值，查询失败时返回错误
+func (receiver *TableSet[Table]) TryGetLong(fieldName string) (int64, error) {
+	return getValue[int64](receiver, fieldName)
+}
+
+// TryGetLongs 获取int64字段的集合，查询失败时返回错误
+func (receiver *TableSet[Table]) TryGetLongs(fieldName string) (collections.List[int64], error) {
+	return getValues[int64](receiver, fieldName)
+}
+
+// TryGetBool 获取单条记录中的单个bool类型字段值，查询失败时返回错误
+func (receiver *TableSet[Table]) TryGetBool(fieldName string) (bool, error) {
+	return getValue[bool](receiver, fieldName)
+}
+
+// TryGetBools 获取bool字段的集合，查询失败时返回错误
+func (receiver *TableSet[Table]) TryGetBools(fieldName string) (collections.List[bool], error) {
+	return getValues[bool](receiver, fieldName)
+}
+
+// TryGetFloat32 获取单条记录中的单个float32类型字段值，查询失败时返回错误
+func (receiver *TableSet[Table]) TryGetFloat32(fieldName string) (float32, error) {
+	return getValue[float32](receiver, fieldName)
+}
+
+// TryGetFloat32s 获取float32字段的集合，查询失败时返回错误
+func (receiver *TableSet[Table]) TryGetFloat32s(fieldName string) (collections.List[float32], error) {
+	return getValues[float32](receiver, fieldName)
+}
+
+// TryGetFloat64 获取单条记录中的单个float64类型字段值，查询失败时返回错误
+func (receiver *TableSet[Table]) TryGetFloat64(fieldName string) (float64, error) {
+	return getValue[float64](receiver, fieldName)
+}
+
+// TryGetFloat64s 获取float64字段的集合，查询失败时返回错误
+func (receiver *TableSet[Table]) TryGetFloat64s(fieldName string) (collections.List[float64], error) {
+	return getValues[float64](receiver, fieldName)
+}
+
+// TryGetDecimal 获取单条记录中的单个decimal.Decimal类型字段值，查询失败时返回错误
+func (receiver *TableSet[Table]) TryGetDecimal(fieldName string) (decimal.Decimal, error) {
+	return getValue[decimal.Decimal](receiver, fieldName)
+}
+
+// TryGetDecimals 获取decimal.Decimal字段的集合，查询失败时返回错误
+func (receiver *TableSet[Table]) TryGetDecimals(fieldName string) (collections.List[decimal.Decimal], error) {
+	return getValues[decimal.Decimal](receiver, fieldName)
+}
+
+// TryGetTime 获取单条记录中的单个time.Time类型字段值，查询失败时返回错误
+func (receiver *TableSet[Table]) TryGetTime(fieldName string) (time.Time, error) {
+	return getValue[time.Time](receiver, fieldName)
+}
+
+// TryGetTimes 获取time.Time字段的集合，查询失败时返回错误
+func (receiver *TableSet[Table]) TryGetTimes(fieldName string) (collections.List[time.Time], error) {
+	return getValues[time.Time](receiver, fieldName)
+}
+
 func (receiver *TableSet[Table]) TruncateTable() error {
 	sql := fmt.Sprintf("truncate TABLE %s;", receiver.tableName) // OPTIMIZE TABLE %s;
 	_, err := receiver.ExecuteSql(sql)
@@ -1265,6 +1518,29 @@ func (receiver *TableSet[Table]) ExecuteSqlToList(sql string, values ...any) col
 	return collections.NewList(lst...)
 }
 
+// TryExecuteSqlToEntity 返回单个对象(执行自定义SQL)，found表示是否找到记录，查询失败时返回错误
+func (receiver *TableSet[Table]) TryExecuteSqlToEntity(sql string, values ...any) (entity Table, found bool, err error) {
+	session := receiver.getOrCreateSession()
+	if session.err != nil {
+		return entity, false, session.err
+	}
+	sql = receiver.nameReplacer.Replace(sql)
+	result := session.getClient().Raw(sql, values...).Find(&entity)
+	return entity, result.Error == nil && result.RowsAffected > 0, result.Error
+}
+
+// TryExecuteSqlToList 返回结果集(执行自定义SQL)，查询失败时返回错误
+func (receiver *TableSet[Table]) TryExecuteSqlToList(sql string, values ...any) (collections.List[Table], error) {
+	var lst []Table
+	session := receiver.getOrCreateSession()
+	if session.err != nil {
+		return collections.NewList[Table](), session.err
+	}
+	sql = receiver.nameReplacer.Replace(sql)
+	err := session.getClient().Raw(sql, values...).Find(&lst).Error
+	return collections.NewList(lst...), err
+}
+
 // Original 返回原生的对象
 func (receiver *TableSet[Table]) Original() *gorm.DB {
 	return receiver.getOrCreateSession().getClient()
@@ -1288,34 +1564,129 @@ func (receiver *TableSet[Table]) GetPrimaryName() {
 	}
 }
 
+// 生成RETURNING子句（sqlserver驱动会转换成OUTPUT）
+func (receiver *TableSet[Table]) returningClause() clause.Returning {
+	var columns []clause.Column
+	for _, name := range receiver.returning {
+		columns = append(columns, clause.Column{Name: name})
+	}
+	return clause.Returning{Columns: columns}
+}
+
+// 在当前Session的连接上（事务中时为同一个事务）新建一个client，按租户路由，不影响当前Session的条件
+func (receiver *TableSet[Table]) newSessionClient(base *gorm.DB) (*gorm.DB, error) {
+	if base == nil {
+		return nil, receiver.err
+	}
+	return receiver.routeTenant(base.Session(&gorm.Session{NewDB: true}), receiver.tenant)
+}
+
+// 在base的连接上按当前的条件（where、order、limit）新建一个client，不影响当前Session
+func (receiver *TableSet[Table]) newConditionClient(base *gorm.DB) (*gorm.DB, error) {
+	gormDB, err := receiver.newSessionClient(base)
+	if err != nil {
+		return nil, err
+	}
+	for _, query := range receiver.whereList.ToArray() {
+		gormDB = gormDB.Where(query.query, query.args...)
+	}
+	for _, order := range receiver.orderList.ToArray() {
+		gormDB = gormDB.Order(order)
+	}
+	if receiver.limit > 0 {
+		gormDB = gormDB.Limit(receiver.limit)
+	}
+	return gormDB, nil
+}
+
+// 按当前的条件查出记录的主键（用于不支持RETURNING的数据库回查）
+func (receiver *TableSet[Table]) findPrimaryList() ([]Table, error) {
+	if len(receiver.primaryName) == 0 {
+		return nil, fmt.Errorf("表：%s 未声明主键，无法回查数据库生成的值", receiver.tableName)
+	}
+	gormDB, err := receiver.newConditionClient(receiver.ormClient)
+	if err != nil {
+		return nil, err
+	}
+	var lst []Table
+	err = gormDB.Select(receiver.primaryName).Find(&lst).Error
+	return lst, err
+}
+
+// 按主键回查记录，并写回po（用于不支持RETURNING的数据库）
+func (receiver *TableSet[Table]) findByPrimary(po *Table) error {
+	if len(receiver.primaryName) == 0 {
+		return fmt.Errorf("表：%s 未声明主键，无法回查数据库生成的值", receiver.tableName)
+	}
+	mapPO := ToMap(po)
+	where := make(map[string]any)
+	for _, name := range receiver.primaryName {
+		where[name] = mapPO[name]
+	}
+
+	gormDB, err := receiver.newSessionClient(receiver.ormClient)
+	if err != nil {
+		return err
+	}
+	if len(receiver.returning) > 0 {
+		gormDB = gormDB.Select(receiver.returning)
+	}
+	return gormDB.Where(where).Limit(1).Find(po).Error
+}
+
+// 驱动是否支持RETURNING子句（clauses为gorm回调中注册的子句）
+func supportReturning(clauses []string) bool {
+	return collections.NewList(clauses...).Contains("RETURNING")
+}
+
 // Clickhouse 返回Clickhouse的对象
 func (receiver *TableSet[Table]) Clickhouse() *mergeTreeSet {
 	return newClickhouse(receiver.getOrCreateSession())
 }
 
-// cleanDirtyConnectionOnError 清理脏连接，解决 clickhouse code: 101 错误
-// 该错误的根本原因：TCP缓冲区残留数据导致协议错误
-// 核心策略：遇到 code: 101 错误时，强制清理连接池中的脏连接
-// 这样下次重试（由MQ层面触发）时，就能获取到干净的连接
-func (receiver *TableSet[Table]) cleanDirtyConnectionOnError(err error) {
-	// 检查是否是 code: 101 错误
-	if strings.Contains(err.Error(), "code: 101") || strings.Contains(err.Error(), "Unexpected packet") {
-		flog.Warningf("出现了101错误,设置 MaxIdleConns=0，强制关闭所有空闲连接")
-		// 关键修复：强制清理连接池中的空闲连接
-		// 原理：code: 101 说明连接池中可能有脏连接（TCP缓冲区有残留数据）
-		// 通过设置 MaxIdleConns=0，强制关闭所有空闲连接
-		// 这样下次MQ重试时，会获取到全新的、干净的连接
-		if gormDB, err := open(receiver.dbContext.dbConfig); err == nil {
-			if sqlDB, err := gormDB.DB(); err == nil {
-				sqlDB.SetMaxIdleConns(0)          // 不保留任何空闲连接
-				time.Sleep(time.Millisecond * 50) // 等待连接池清理
-				// 恢复配置
-				if receiver.dbContext.dbConfig.PoolMaxSize > 0 {
-					sqlDB.SetMaxIdleConns(receiver.dbContext.dbConfig.PoolMaxSize / 3)
-				}
+// 获取单条记录中的单个字段值
+func getValue[T any, Table any](receiver *TableSet[Table], fieldName string) (T, error) {
+	var val T
+	err := receiver.query("GetValue", func(client *gorm.DB) error {
+		rows, err := client.Select(fieldName).Limit(1).Rows()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = rows.Close()
+		}()
+		for rows.Next() {
+			if err = rows.Scan(&val); err != nil {
+				return err
 			}
 		}
-	}
+		return rows.Err()
+	})
+	return val, err
+}
+
+// 获取字段值的集合
+func getValues[T any, Table any](receiver *TableSet[Table], fieldName string) (collections.List[T], error) {
+	lst := collections.NewList[T]()
+	err := receiver.query("GetValues", func(client *gorm.DB) error {
+		rows, err := client.Select(fieldName).Rows()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = rows.Close()
+		}()
+		lst.Clear()
+		var val T
+		for rows.Next() {
+			if err = rows.Scan(&val); err != nil {
+				return err
+			}
+			lst.Add(val)
+		}
+		return rows.Err()
+	})
+	return lst, err
 }
 
 // 大写字母，转蛇形
@@ -0,0 +1,317 @@
+package data
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/farseer-go/fs/asyncLocal"
+	"github.com/farseer-go/fs/container"
+	"github.com/farseer-go/fs/exception"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TenantStrategy 多租户的隔离方式
+type TenantStrategy int
+
+const (
+	TenantDatabase    TenantStrategy = iota // 每个租户一个数据库
+	TenantSchema                            // 每个租户一个schema（同一个数据库）
+	TenantSharedTable                       // 所有租户共享表，通过租户字段区分
+)
+
+// 共享表时，默认的租户字段名
+const defaultTenantColumn = "tenant_id"
+
+// ITenantResolver 多租户解析器，注册到容器时的名称为数据库配置名称（./config.yaml的Database节点名）
+// 注册后，InitContext创建的TableSet、事务、Original都会按当前的租户自动路由
+type ITenantResolver interface {
+	// GetTenant 获取当前的租户，返回空时使用注册的上下文（不路由）
+	// 通常返回data.CurrentTenant()（由data.SetTenant设置到asyncLocal），也可以从请求的上下文中获取
+	GetTenant() string
+	// Resolve 获取租户的路由，每个Session（TableSet的一次链式调用、Original、开启事务）调用一次，耗时的查询需要自行缓存
+	Resolve(tenant string) (TenantRoute, error)
+}
+
+// TenantRoute 租户的路由
+type TenantRoute struct {
+	Strategy         TenantStrategy // 隔离方式
+	ConnectionString string         // TenantDatabase：租户数据库的配置，格式同RegisterInternalContext（DataType=mysql,ConnectionString=...）
+	Schema           string         // TenantSchema：租户的schema名称，默认为租户
+	Column           string         // TenantSharedTable：区分租户的字段名，默认tenant_id
+	Provision        bool           // 首次使用时创建租户的数据库或schema，并迁移TableSet的表结构
+}
+
+// 当前协程的租户
+var routineTenant = asyncLocal.New[string]()
+
+// SetTenant 设置当前协程的租户
+func SetTenant(tenant string) {
+	routineTenant.Set(tenant)
+}
+
+// CurrentTenant 获取当前协程的租户（SetTenant设置的值）
+func CurrentTenant() string {
+	return routineTenant.Get()
+}
+
+// ClearTenant 清除当前协程的租户
+func ClearTenant() {
+	routineTenant.Remove()
+}
+
+// 当前的租户
+type tenant struct {
+	name     string
+	route    TenantRoute
+	dbConfig *dbConfig // TenantDatabase：租户数据库的配置
+}
+
+var (
+	tenantConfigs       = make(map[string]*dbConfig) // 租户数据库的配置，key为租户的连接字符串
+	provisioned         = make(map[string]bool)      // 已创建数据库或schema的租户
+	migratedTables      = make(map[string]bool)      // 已迁移表结构的租户表
+	tenantConfigLock    sync.Mutex
+	tenantProvisionLock sync.Mutex
+)
+
+// 获取当前的租户，未注册租户解析器或当前没有租户时返回nil
+func (receiver *dbConfig) getTenant() (*tenant, error) {
+	if receiver.keyName == "" || receiver.dynamic || !container.IsRegister[ITenantResolver](receiver.keyName) {
+		return nil, nil
+	}
+	resolver := container.Resolve[ITenantResolver](receiver.keyName)
+	name := resolver.GetTenant()
+	if name == "" {
+		return nil, nil
+	}
+
+	route, err := resolver.Resolve(name)
+	if err != nil {
+		return nil, fmt.Errorf("获取租户%s的路由失败：%w", name, err)
+	}
+	current := &tenant{name: name, route: route}
+	switch route.Strategy {
+	case TenantDatabase:
+		if current.dbConfig, err = getTenantConfig(route.ConnectionString); err != nil {
+			return nil, fmt.Errorf("租户%s的ConnectionString配置不正确：%w", name, err)
+		}
+	case TenantSchema:
+		if current.route.Schema == "" {
+			current.route.Schema = name
+		}
+	case TenantSharedTable:
+		if current.route.Column == "" {
+			current.route.Column = defaultTenantColumn
+		}
+	}
+
+	if route.Provision && route.Strategy != TenantSharedTable {
+		if err = receiver.provisionTenant(current); err != nil {
+			return nil, err
+		}
+	}
+	return current, nil
+}
+
+// 租户数据库的配置（动态连接，长时间不用时会关闭连接池）
+func getTenantConfig(configString string) (*dbConfig, error) {
+	tenantConfigLock.Lock()
+	defer tenantConfigLock.Unlock()
+
+	if config, exists := tenantConfigs[configString]; exists {
+		return config, nil
+	}
+	ins := NewInternalContext(configString)
+	if ins.dbConfig.DataType == "" || ins.dbConfig.ConnectionString == "" {
+		return nil, fmt.Errorf("DataType、ConnectionString不能为空")
+	}
+	tenantConfigs[configString] = ins.dbConfig
+	return ins.dbConfig, nil
+}
+
+// 首次使用时，在注册的数据库中创建租户的数据库或schema
+func (receiver *dbConfig) provisionTenant(current *tenant) error {
+	tenantProvisionLock.Lock()
+	defer tenantProvisionLock.Unlock()
+
+	key := receiver.keyName + "/" + current.name
+	if provisioned[key] {
+		return nil
+	}
+	// 使用注册的数据库，不能再按租户路由
+	gormDB, err := openPool(receiver)
+	if err != nil {
+		return err
+	}
+	gormDB = gormDB.Session(&gorm.Session{})
+
+	switch current.route.Strategy {
+	case TenantDatabase:
+		// sqlite打开时会自动创建数据库文件
+		if current.dbConfig.DataType != "sqlite" {
+			err = createIfNotExists(gormDB, receiver.DataType, current.dbConfig.databaseName, false)
+		}
+	case TenantSchema:
+		err = createIfNotExists(gormDB, receiver.DataType, current.route.Schema, true)
+	}
+	if err != nil {
+		return fmt.Errorf("创建租户%s的数据库失败：%w", current.name, err)
+	}
+	provisioned[key] = true
+	return nil
+}
+
+// 创建数据库或schema（mysql、clickhouse的schema即数据库）
+func createIfNotExists(gormDB *gorm.DB, dataType string, name string, isSchema bool) error {
+	if name == "" {
+		return fmt.Errorf("数据库名称不能为空")
+	}
+
+	var existsSql, createSql string
+	switch dataType {
+	case "mysql":
+		existsSql, createSql = "SELECT COUNT(*) FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", "CREATE DATABASE "
+	case "clickhouse":
+		existsSql, createSql = "SELECT COUNT(*) FROM system.databases WHERE name = ?", "CREATE DATABASE "
+	case "postgresql", "postgres":
+		existsSql, createSql = "SELECT COUNT(*) FROM pg_database WHERE datname = ?", "CREATE DATABASE "
+		if isSchema {
+			existsSql, createSql = "SELECT COUNT(*) FROM information_schema.schemata WHERE schema_name = ?", "CREATE SCHEMA "
+		}
+	case "sqlserver", "mssql":
+		existsSql, createSql = "SELECT COUNT(*) FROM sys.databases WHERE name = ?", "CREATE DATABASE "
+		if isSchema {
+			existsSql, createSql = "SELECT COUNT(*) FROM sys.schemas WHERE name = ?", "CREATE SCHEMA "
+		}
+	default:
+		return fmt.Errorf("%s不支持自动创建数据库", dataType)
+	}
+
+	var count int64
+	if err := gormDB.Raw(existsSql, name).Scan(&count).Error; err != nil || count > 0 {
+		return err
+	}
+	if err := gormDB.Exec(createSql + gormDB.Statement.Quote(name)).Error; err != nil {
+		// 多个实例同时创建时，其它实例已创建成功
+		if gormDB.Raw(existsSql, name).Scan(&count).Error == nil && count > 0 {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// 租户使用的数据库配置：TenantDatabase时为租户数据库的配置，否则为注册的配置
+func (receiver *tenant) getDbConfig(dbConfig *dbConfig) *dbConfig {
+	if receiver != nil && receiver.dbConfig != nil {
+		return receiver.dbConfig
+	}
+	return dbConfig
+}
+
+// 租户路由后的表名：TenantSchema时加上schema
+func (receiver *tenant) tableName(tableName string) string {
+	if receiver != nil && receiver.route.Strategy == TenantSchema {
+		return receiver.route.Schema + "." + tableName
+	}
+	return tableName
+}
+
+// 按租户路由：TenantSchema使用schema下的表，TenantSharedTable由TenantPlugin按租户字段过滤、写入
+// current为创建Session时解析的租户，为nil时不路由
+func (receiver *TableSet[Table]) routeTenant(gormDB *gorm.DB, current *tenant) (*gorm.DB, error) {
+	if len(receiver.tableName) > 0 {
+		gormDB = gormDB.Table(current.tableName(receiver.tableName))
+	}
+	if current == nil {
+		return gormDB, nil
+	}
+	if current.route.Strategy == TenantSharedTable {
+		gormDB = gormDB.Set(tenantColumnKey, current.route.Column).Set(tenantValueKey, current.name)
+	}
+
+	// 首次使用时迁移租户的表结构
+	if current.route.Provision && current.route.Strategy != TenantSharedTable && receiver.migrateParam != nil {
+		if err := receiver.migrateTenant(current, gormDB); err != nil {
+			return nil, err
+		}
+	}
+	return gormDB, nil
+}
+
+// 在租户的数据库（schema）中创建表、索引，每个租户的每张表只执行一次
+func (receiver *TableSet[Table]) migrateTenant(current *tenant, gormDB *gorm.DB) error {
+	tenantProvisionLock.Lock()
+	defer tenantProvisionLock.Unlock()
+
+	key := receiver.dbContext.dbConfig.keyName + "/" + current.name + "/" + receiver.tableName
+	if migratedTables[key] {
+		return nil
+	}
+	ts := &TableSet[Table]{
+		dbContext:    receiver.dbContext,
+		tableName:    current.tableName(receiver.tableName),
+		primaryName:  receiver.primaryName,
+		nameReplacer: receiver.nameReplacer,
+		ormClient:    gormDB,
+	}
+	if err := exception.TryCatch(func() {
+		ts.CreateTable(receiver.migrateParam)
+		ts.CreateIndex()
+	}); err != nil {
+		return fmt.Errorf("迁移租户%s的表%s失败：%w", current.name, receiver.tableName, err)
+	}
+	migratedTables[key] = true
+	return nil
+}
+
+// TenantPlugin 共享表的多租户：查询、修改、删除时按租户字段过滤，新增时写入租户字段
+type TenantPlugin struct{}
+
+const (
+	tenantColumnKey = "data:tenant_column"
+	tenantValueKey  = "data:tenant"
+)
+
+func (op *TenantPlugin) Name() string {
+	return "tenantPlugin"
+}
+
+func (op *TenantPlugin) Initialize(db *gorm.DB) (err error) {
+	_ = db.Callback().Create().Before("gorm:create").Register("tenant_create", op.create)
+	_ = db.Callback().Query().Before("gorm:query").Register("tenant_where", op.where)
+	_ = db.Callback().Update().Before("gorm:update").Register("tenant_where", op.where)
+	_ = db.Callback().Delete().Before("gorm:delete").Register("tenant_where", op.where)
+	_ = db.Callback().Row().Before("gorm:row").Register("tenant_where", op.where)
+	return
+}
+
+// 当前语句的租户字段、租户
+func (op *TenantPlugin) tenant(db *gorm.DB) (string, any, bool) {
+	column, exists := db.Get(tenantColumnKey)
+	if !exists {
+		return "", nil, false
+	}
+	value, _ := db.Get(tenantValueKey)
+	return column.(string), value, true
+}
+
+// 按租户字段过滤
+func (op *TenantPlugin) where(db *gorm.DB) {
+	if column, value, exists := op.tenant(db); exists {
+		db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: column}, Value: value}}})
+	}
+}
+
+// 写入租户字段（PO中需要有对应的字段）
+func (op *TenantPlugin) create(db *gorm.DB) {
+	column, value, exists := op.tenant(db)
+	if !exists || db.Statement.Schema == nil {
+		return
+	}
+	if field := db.Statement.Schema.LookUpField(column); field != nil {
+		// 批量新增时，每一行都写入租户字段
+		db.Statement.SetColumn(field.DBName, value, true)
+	}
+}
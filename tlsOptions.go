@@ -0,0 +1,137 @@
+package data
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// TLS的校验方式
+const (
+	TLSDisable    = "disable"     // 不使用TLS（默认）
+	TLSRequire    = "require"     // 加密，但不校验证书
+	TLSVerifyCA   = "verify-ca"   // 校验证书链，不校验主机名
+	TLSVerifyFull = "verify-full" // 校验证书链和主机名
+)
+
+// TLSOptions 统一的TLS配置，由各驱动转换成原生的方式（实现ITLSDriver）
+type TLSOptions struct {
+	Mode       string // 校验方式：disable（默认）、require、verify-ca、verify-full
+	CAFile     string // CA证书文件，不设置时使用系统的根证书
+	CertFile   string // 客户端证书文件（双向认证）
+	KeyFile    string // 客户端私钥文件
+	ServerName string // 校验证书的主机名，默认为连接的主机
+}
+
+// 是否启用了TLS
+func (receiver TLSOptions) enabled() bool {
+	mode := strings.ToLower(receiver.Mode)
+	return mode != "" && mode != TLSDisable
+}
+
+// 转换成tls.Config，host为连接的主机
+func (receiver TLSOptions) build(host string) (*tls.Config, error) {
+	config := &tls.Config{ServerName: receiver.ServerName}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+
+	if receiver.CAFile != "" {
+		pem, err := os.ReadFile(receiver.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取TLS的CA证书失败：%w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("TLS的CA证书%s格式不正确", receiver.CAFile)
+		}
+	}
+
+	if receiver.CertFile != "" || receiver.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(receiver.CertFile, receiver.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取TLS的客户端证书失败：%w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	switch strings.ToLower(receiver.Mode) {
+	case TLSRequire:
+		config.InsecureSkipVerify = true
+	case TLSVerifyCA:
+		// 跳过默认的校验（包含主机名），只校验证书链
+		config.InsecureSkipVerify = true
+		config.VerifyPeerCertificate = verifyCertificateChain(config.RootCAs)
+	case TLSVerifyFull:
+	default:
+		return nil, fmt.Errorf("TLS.Mode配置不正确：%s", receiver.Mode)
+	}
+	return config, nil
+}
+
+// 只校验证书链，不校验主机名
+func verifyCertificateChain(roots *x509.CertPool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("服务端没有提供证书")
+		}
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for index, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs[index] = cert
+		}
+		opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+		for _, cert := range certs[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := certs[0].Verify(opts)
+		return err
+	}
+}
+
+// TLS配置
+func (receiver *dbConfig) getTLSOptions() TLSOptions {
+	return TLSOptions{Mode: receiver.TlsMode, CAFile: receiver.TlsCaFile, CertFile: receiver.TlsCertFile, KeyFile: receiver.TlsKeyFile, ServerName: receiver.TlsServerName}
+}
+
+// 未配置TLS时返回nil（使用连接字符串中的TLS配置）
+func (receiver *dbConfig) buildTLSConfig(connectionString string) (*tls.Config, error) {
+	if !receiver.getTLSOptions().enabled() {
+		return nil, nil
+	}
+	info, err := receiver.parseConnectionString(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	return receiver.getTLSOptions().build(info.Host)
+}
+
+// 配置了TLS时，由驱动使用tls.Config创建连接器，打开数据库
+func (receiver *dbConfig) getTLSDriver(connectionString string) (gorm.Dialector, error) {
+	tlsDriver, isTLSDriver := receiver.GetDataDriver().(ITLSDriver)
+	if !isTLSDriver {
+		return nil, fmt.Errorf("%s不支持TLS配置", receiver.DataType)
+	}
+	tlsConfig, err := receiver.buildTLSConfig(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	connector, err := tlsDriver.TLSConnector(connectionString, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// 使用了密钥占位符时，每次新建连接都重新解析密钥
+	if receiver.hasSecret() {
+		connector = &secretConnector{dbConfig: receiver, driver: connector.Driver(), tlsDriver: tlsDriver, tlsConfig: tlsConfig}
+	}
+	return tlsDriver.GetDriverWithConn(sql.OpenDB(connector)), nil
+}
@@ -0,0 +1,89 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Tx 显式的事务对象，不依赖协程上下文，可以跨协程、跨层传递
+// 通过TableSet.InTx、DefaultRepository.InTx绑定后执行的SQL，都在该事务中
+type Tx struct {
+	dbConfig       *dbConfig
+	gormDB         *gorm.DB
+	nameReplacer   *strings.Replacer // 替换dbName、tableName
+	callbacks      txCallbacks       // 提交、回滚后的回调
+	callbackLock   sync.Mutex        // Tx可以跨协程传递，注册、取出回调时加锁
+	tenantDatabase bool              // 是否在租户的数据库（TenantDatabase）中开启的事务
+}
+
+// BeginTx 开启显式的事务，需要手动调用Commit或Rollback
+// 与Begin、Transaction不同，该事务不会保存到协程上下文中
+func (receiver *internalContext) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	current, err := receiver.dbConfig.getTenant()
+	if err != nil {
+		return nil, err
+	}
+	txConfig := current.getDbConfig(receiver.dbConfig)
+	gormDB, err := openPool(txConfig)
+	if err != nil {
+		return nil, err
+	}
+	gormDB = gormDB.WithContext(ctx).Begin(opts)
+	if gormDB.Error != nil {
+		return nil, receiver.dbConfig.translateError(gormDB.Error)
+	}
+	trackTransaction(receiver.dbConfig, gormDB, true)
+	return &Tx{dbConfig: receiver.dbConfig, gormDB: gormDB, nameReplacer: receiver.nameReplacer, tenantDatabase: txConfig != receiver.dbConfig}, nil
+}
+
+// Commit 事务提交
+func (receiver *Tx) Commit() error {
+	err := receiver.dbConfig.translateError(receiver.gormDB.Commit().Error)
+	untrackTransaction(receiver.gormDB)
+	callbacks := receiver.takeCallbacks()
+	if err == nil {
+		runTxCallbacks("OnCommit", callbacks.onCommit)
+	} else {
+		runTxCallbacks("OnRollback", callbacks.onRollback)
+	}
+	return err
+}
+
+// Rollback 事务回滚
+func (receiver *Tx) Rollback() error {
+	err := receiver.dbConfig.translateError(receiver.gormDB.Rollback().Error)
+	untrackTransaction(receiver.gormDB)
+	callbacks := receiver.takeCallbacks()
+	runTxCallbacks("OnRollback", callbacks.onRollback)
+	return err
+}
+
+// Original 返回原生的对象
+func (receiver *Tx) Original() *gorm.DB {
+	return receiver.gormDB.Session(&gorm.Session{})
+}
+
+// ExecuteSql 执行自定义SQL
+func (receiver *Tx) ExecuteSql(sql string, values ...any) (int64, error) {
+	sql = receiver.nameReplacer.Replace(sql)
+	result := receiver.Original().Exec(sql, values...)
+	return result.RowsAffected, result.Error
+}
+
+// ExecuteSqlToResult 返回结果(执行自定义SQL)
+func (receiver *Tx) ExecuteSqlToResult(arrayOrEntity any, sql string, values ...any) (int64, error) {
+	sql = receiver.nameReplacer.Replace(sql)
+	result := receiver.Original().Raw(sql, values...).Find(arrayOrEntity)
+	return result.RowsAffected, result.Error
+}
+
+// ExecuteSqlToValue 返回单个字段值(执行自定义SQL)
+func (receiver *Tx) ExecuteSqlToValue(field any, sql string, values ...any) (int64, error) {
+	sql = receiver.nameReplacer.Replace(sql)
+	result := receiver.Original().Raw(sql, values...).Scan(field)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,98 @@
+package data
+
+import (
+	"github.com/farseer-go/fs/exception"
+	"github.com/farseer-go/fs/flog"
+	"github.com/timandy/routine"
+)
+
+// 同一个协程下事务的回调，下标0为最外层的事务，之后每个保存点（嵌套事务）各一层
+var routineTxCallbacks = make(map[string]routine.ThreadLocal[[]*txCallbacks])
+
+// 事务提交、回滚后的回调
+type txCallbacks struct {
+	onCommit   []func()
+	onRollback []func()
+}
+
+// 合并嵌套事务的回调：嵌套事务提交后，由外层事务决定最终是提交还是回滚
+func (receiver *txCallbacks) merge(inner *txCallbacks) {
+	receiver.onCommit = append(receiver.onCommit, inner.onCommit...)
+	receiver.onRollback = append(receiver.onRollback, inner.onRollback...)
+}
+
+// 依次执行回调，某个回调异常时不影响其它回调
+func runTxCallbacks(name string, callbacks []func()) {
+	for _, callback := range callbacks {
+		if err := exception.TryCatch(callback); err != nil {
+			flog.Warningf("执行%s回调时出现异常：%s", name, err.Error())
+		}
+	}
+}
+
+// OnCommit 注册事务提交后的回调（如发送MQ、清除缓存），回滚时不会执行
+// 在嵌套事务中注册时，等到最外层的事务提交后才执行；不在事务中时，立即执行
+func (receiver *internalContext) OnCommit(callback func()) {
+	frames := getRoutineTxCallbacks(receiver.dbConfig.keyName).Get()
+	if len(frames) == 0 {
+		runTxCallbacks("OnCommit", []func(){callback})
+		return
+	}
+	frame := frames[len(frames)-1]
+	frame.onCommit = append(frame.onCommit, callback)
+}
+
+// OnRollback 注册事务回滚后的回调（包括提交失败）
+// 在嵌套事务中注册时，嵌套事务或外层事务任意一个回滚都会执行；不在事务中时，立即执行
+func (receiver *internalContext) OnRollback(callback func()) {
+	frames := getRoutineTxCallbacks(receiver.dbConfig.keyName).Get()
+	if len(frames) == 0 {
+		runTxCallbacks("OnRollback", []func(){callback})
+		return
+	}
+	frame := frames[len(frames)-1]
+	frame.onRollback = append(frame.onRollback, callback)
+}
+
+// 开启事务或创建保存点时，增加一层回调
+func (receiver *internalContext) pushTxCallbacks() {
+	frames := getRoutineTxCallbacks(receiver.dbConfig.keyName).Get()
+	getRoutineTxCallbacks(receiver.dbConfig.keyName).Set(append(frames, &txCallbacks{}))
+}
+
+// 提交或回滚时，取出最内层的回调
+func (receiver *internalContext) popTxCallbacks() *txCallbacks {
+	frames := getRoutineTxCallbacks(receiver.dbConfig.keyName).Get()
+	if len(frames) == 0 {
+		return &txCallbacks{}
+	}
+	if len(frames) == 1 {
+		getRoutineTxCallbacks(receiver.dbConfig.keyName).Remove()
+	} else {
+		getRoutineTxCallbacks(receiver.dbConfig.keyName).Set(frames[:len(frames)-1])
+	}
+	return frames[len(frames)-1]
+}
+
+// OnCommit 注册事务提交后的回调，回滚时不会执行
+func (receiver *Tx) OnCommit(callback func()) {
+	receiver.callbackLock.Lock()
+	defer receiver.callbackLock.Unlock()
+	receiver.callbacks.onCommit = append(receiver.callbacks.onCommit, callback)
+}
+
+// OnRollback 注册事务回滚后的回调（包括提交失败）
+func (receiver *Tx) OnRollback(callback func()) {
+	receiver.callbackLock.Lock()
+	defer receiver.callbackLock.Unlock()
+	receiver.callbacks.onRollback = append(receiver.callbacks.onRollback, callback)
+}
+
+// 提交或回滚时，取出已注册的回调
+func (receiver *Tx) takeCallbacks() txCallbacks {
+	receiver.callbackLock.Lock()
+	defer receiver.callbackLock.Unlock()
+	callbacks := receiver.callbacks
+	receiver.callbacks = txCallbacks{}
+	return callbacks
+}
@@ -0,0 +1,140 @@
+package data
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/farseer-go/fs/flog"
+	"github.com/timandy/routine"
+	"gorm.io/gorm"
+)
+
+// OpenTransaction 未提交的事务，用于诊断事务泄露、长事务
+type OpenTransaction struct {
+	KeyName     string        // 上下文配置名（config.yaml中的Database节点名），动态连接为脱敏后的连接字符串
+	StartAt     time.Time     // 开启时间
+	Elapsed     time.Duration // 已持续的时间
+	CallSite    string        // 开启事务的调用位置
+	GoroutineId uint64        // 开启事务的协程
+	Explicit    bool          // 是否为BeginTx开启的显式事务
+}
+
+// 跟踪中的事务
+type trackedTransaction struct {
+	OpenTransaction
+	dbConfig *dbConfig
+	gormDB   *gorm.DB
+	warned   int32 // 是否已提示过长事务（1：已提示）
+	aborted  int32 // 是否因超时被强制回滚（1：已回滚）
+}
+
+var (
+	openTransactions sync.Map  // *gorm.DB -> *trackedTransaction
+	txMonitorOnce    sync.Once // 长事务检查只启动一次
+)
+
+// 获取调用位置时跳过的包（本模块、运行时、异常处理）
+var callSiteSkipPrefixes = []string{"github.com/farseer-go/data.", "runtime.", "github.com/farseer-go/fs/exception."}
+
+// GetOpenTransactions 获取当前所有未提交的事务，按开启时间排序
+func GetOpenTransactions() []OpenTransaction {
+	var lst []OpenTransaction
+	openTransactions.Range(func(_, value any) bool {
+		tracked := value.(*trackedTransaction)
+		openTransaction := tracked.OpenTransaction
+		openTransaction.Elapsed = time.Since(tracked.StartAt)
+		lst = append(lst, openTransaction)
+		return true
+	})
+	sort.Slice(lst, func(i, j int) bool { return lst[i].StartAt.Before(lst[j].StartAt) })
+	return lst
+}
+
+// 开始跟踪事务
+func trackTransaction(dbConfig *dbConfig, gormDB *gorm.DB, explicit bool) {
+	openTransactions.Store(gormDB, &trackedTransaction{
+		OpenTransaction: OpenTransaction{
+			KeyName:     dbConfig.displayName(),
+			StartAt:     time.Now(),
+			CallSite:    getCallSite(),
+			GoroutineId: routine.Goid(),
+			Explicit:    explicit,
+		},
+		dbConfig: dbConfig,
+		gormDB:   gormDB,
+	})
+
+	// 配置了长事务的阈值时，启动后台检查
+	if dbConfig.TxWarnSeconds > 0 || dbConfig.TxAbortSeconds > 0 {
+		txMonitorOnce.Do(func() {
+			go monitorTransactions()
+		})
+	}
+}
+
+// 停止跟踪事务
+func untrackTransaction(gormDB *gorm.DB) {
+	openTransactions.Delete(gormDB)
+}
+
+// 事务是否已因超时被强制回滚
+func isAbortedTransaction(gormDB *gorm.DB) bool {
+	value, exists := openTransactions.Load(gormDB)
+	return exists && atomic.LoadInt32(&value.(*trackedTransaction).aborted) == 1
+}
+
+// 定时检查长事务：超过TxWarnSeconds时提示，超过TxAbortSeconds时强制回滚
+// 强制回滚时事务可能仍在被持有者使用：直接回滚底层的*sql.Tx（并发安全，会等待执行中的语句结束），不修改持有者的gorm.DB
+func monitorTransactions() {
+	for range time.Tick(time.Second) {
+		openTransactions.Range(func(_, value any) bool {
+			tracked := value.(*trackedTransaction)
+			elapsed := time.Since(tracked.StartAt)
+
+			if abortSeconds := tracked.dbConfig.TxAbortSeconds; abortSeconds > 0 && elapsed > time.Duration(abortSeconds)*time.Second {
+				if atomic.CompareAndSwapInt32(&tracked.aborted, 0, 1) {
+					flog.Warningf("[config.yaml]Database.%s 事务已持续%s，超过%d秒，强制回滚。开启位置：%s", tracked.KeyName, elapsed.Round(time.Second), abortSeconds, tracked.CallSite)
+					if committer, isCommitter := tracked.gormDB.Statement.ConnPool.(gorm.TxCommitter); isCommitter {
+						_ = committer.Rollback()
+					}
+				}
+				return true
+			}
+
+			if warnSeconds := tracked.dbConfig.TxWarnSeconds; warnSeconds > 0 && elapsed > time.Duration(warnSeconds)*time.Second && atomic.CompareAndSwapInt32(&tracked.warned, 0, 1) {
+				flog.Warningf("[config.yaml]Database.%s 事务已持续%s，超过%d秒，可能忘记了Commit或Rollback。开启位置：%s", tracked.KeyName, elapsed.Round(time.Second), warnSeconds, tracked.CallSite)
+			}
+			return true
+		})
+	}
+}
+
+// 获取开启事务的调用位置（跳过本模块内部的调用）
+func getCallSite() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !isSkipCallSite(frame.Function) {
+			return fmt.Sprintf("%s:%d %s", frame.File, frame.Line, frame.Function)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// 是否为需要跳过的调用
+func isSkipCallSite(function string) bool {
+	for _, prefix := range callSiteSkipPrefixes {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
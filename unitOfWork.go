@@ -0,0 +1,165 @@
+package data
+
+import (
+	"fmt"
+
+	"github.com/farseer-go/fs/container"
+	"github.com/farseer-go/fs/core"
+	"github.com/farseer-go/fs/exception"
+	"github.com/farseer-go/fs/trace"
+)
+
+// UnitOfWork 跨多个数据库的工作单元
+// 支持事务的数据库：按添加顺序开启事务、执行、提交，任意一个失败时，回滚未提交的，补偿已提交的
+// 不支持事务的数据库（如clickhouse）：在所有事务提交成功后才执行，失败时执行兜底（如写入outbox），兜底也失败时补偿已提交的
+type UnitOfWork struct {
+	participants []*unitOfWorkParticipant
+}
+
+// 工作单元的参与者
+type unitOfWorkParticipant struct {
+	dbName       string                // 数据库配置名称（Database节点）
+	dbContext    *internalContext      // 数据库上下文
+	executeFn    func()                // 数据库操作
+	compensateFn func()                // 补偿操作：已提交后，其它参与者失败时执行
+	fallbackFn   func(err error) error // 兜底操作：不支持事务的数据库执行失败时执行，返回nil表示已兜底（如写入outbox）
+	began        bool                  // 是否已开启事务
+	committed    bool                  // 是否已提交（不支持事务的数据库，执行成功即为已提交）
+}
+
+// NewUnitOfWork 创建跨多个数据库的工作单元
+func NewUnitOfWork() *UnitOfWork {
+	return &UnitOfWork{}
+}
+
+// Add 添加参与者，dbName为数据库配置名称，executeFn中对该数据库的操作都在工作单元中执行
+func (receiver *UnitOfWork) Add(dbName string, executeFn func()) *UnitOfWork {
+	receiver.participants = append(receiver.participants, &unitOfWorkParticipant{dbName: dbName, executeFn: executeFn})
+	return receiver
+}
+
+// Compensate 设置最后添加的参与者的补偿操作：该参与者已提交后，其它参与者失败时执行
+func (receiver *UnitOfWork) Compensate(compensateFn func()) *UnitOfWork {
+	if len(receiver.participants) > 0 {
+		receiver.participants[len(receiver.participants)-1].compensateFn = compensateFn
+	}
+	return receiver
+}
+
+// Fallback 设置最后添加的参与者的兜底操作：不支持事务的数据库执行失败时执行（如使用OutboxFallback写入发件箱，稍后重新投递）
+// 返回nil表示已兜底，工作单元视为成功
+func (receiver *UnitOfWork) Fallback(fallbackFn func(err error) error) *UnitOfWork {
+	if len(receiver.participants) > 0 {
+		receiver.participants[len(receiver.participants)-1].fallbackFn = fallbackFn
+	}
+	return receiver
+}
+
+// Execute 执行工作单元
+func (receiver *UnitOfWork) Execute() (err error) {
+	traceHand := trace.Manager().TraceHand("工作单元")
+	defer func() { traceHand.End(err) }()
+
+	var transactional, nonTransactional []*unitOfWorkParticipant
+	for _, participant := range receiver.participants {
+		if !container.IsRegister[core.ITransaction](participant.dbName) {
+			return fmt.Errorf("工作单元：[config.yaml]Database.%s 未注册", participant.dbName)
+		}
+		participant.dbContext = container.Resolve[core.ITransaction](participant.dbName).(*internalContext)
+		if participant.dbContext.dbConfig.supportTransaction() {
+			transactional = append(transactional, participant)
+		} else {
+			nonTransactional = append(nonTransactional, participant)
+		}
+	}
+
+	// 开启事务，并执行数据库操作
+	for _, participant := range transactional {
+		if err = participant.dbContext.Begin(); err != nil {
+			participant.trace("开启事务", err)
+			receiver.rollback(transactional)
+			return err
+		}
+		participant.began = true
+	}
+	for _, participant := range transactional {
+		if err = participant.execute(); err != nil {
+			participant.trace("执行", err)
+			receiver.rollback(transactional)
+			return err
+		}
+	}
+
+	// 按顺序提交，失败时回滚未提交的，补偿已提交的
+	for _, participant := range transactional {
+		err = participant.dbContext.commit()
+		participant.began = false
+		participant.trace("提交", err)
+		if err != nil {
+			receiver.rollback(transactional)
+			receiver.compensate()
+			return err
+		}
+		participant.committed = true
+	}
+
+	// 不支持事务的数据库，在所有事务提交后才执行，避免写入已回滚的数据
+	for _, participant := range nonTransactional {
+		err = participant.execute()
+		participant.trace("执行", err)
+		if err != nil && participant.fallbackFn != nil {
+			err = participant.fallbackFn(err)
+			participant.trace("兜底", err)
+			if err == nil {
+				continue
+			}
+		}
+		if err != nil {
+			receiver.compensate()
+			return err
+		}
+		participant.committed = true
+	}
+	return nil
+}
+
+// 回滚已开启、未提交的事务
+func (receiver *UnitOfWork) rollback(participants []*unitOfWorkParticipant) {
+	for _, participant := range participants {
+		if participant.began {
+			participant.dbContext.Rollback()
+			participant.began = false
+			participant.trace("回滚", nil)
+		}
+	}
+}
+
+// 倒序补偿已提交的参与者
+func (receiver *UnitOfWork) compensate() {
+	for i := len(receiver.participants) - 1; i >= 0; i-- {
+		participant := receiver.participants[i]
+		if !participant.committed || participant.compensateFn == nil {
+			continue
+		}
+		err := exception.TryCatch(participant.compensateFn)
+		participant.trace("补偿", err)
+	}
+}
+
+// 执行数据库操作，异常时转换成错误
+func (receiver *unitOfWorkParticipant) execute() (err error) {
+	exception.Try(func() {
+		receiver.executeFn()
+		if tx := getRoutineOrmClient(receiver.dbName).Get(); tx != nil {
+			err = tx.Error
+		}
+	}).CatchException(func(exp any) {
+		err = panicToError(exp)
+	})
+	return err
+}
+
+// 记录参与者的执行结果
+func (receiver *unitOfWorkParticipant) trace(action string, err error) {
+	trace.Manager().TraceHand(fmt.Sprintf("工作单元[%s]：%s", receiver.dbName, action)).End(err)
+}